@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/bernardosecades/sharesecret/cmd"
+
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = "Usage: apikey create <name>|revoke <name>|list"
+
+func main() {
+
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	keys, err := cmd.NewAPIKeyFileFromEnv()
+	if err != nil {
+		log.Fatal("Error to load API keys: ", err)
+	}
+
+	switch {
+	case os.Args[1] == "create" && len(os.Args) == 3:
+		key, err := keys.Create(os.Args[2])
+		if err != nil {
+			log.Fatal("Error to create API key: ", err)
+		}
+
+		fmt.Println("API key created, it is not shown again:")
+		fmt.Println(key)
+	case os.Args[1] == "revoke" && len(os.Args) == 3:
+		if err := keys.Revoke(os.Args[2]); err != nil {
+			log.Fatal("Error to revoke API key: ", err)
+		}
+
+		fmt.Printf("Revoked %s\n", os.Args[2])
+	case os.Args[1] == "list" && len(os.Args) == 2:
+		list, err := keys.List()
+		if err != nil {
+			log.Fatal("Error to list API keys: ", err)
+		}
+
+		for _, k := range list {
+			fmt.Printf("%s\t%s\n", k.Name, k.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+	default:
+		log.Fatal(usage)
+	}
+}
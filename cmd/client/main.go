@@ -7,8 +7,11 @@ import (
 	"os"
 	"time"
 
+	"github.com/bernardosecades/sharesecret/cmd"
 	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 )
 
 func main() {
@@ -16,7 +19,17 @@ func main() {
 	host := os.Getenv("SHARESECRET_SERVER_HOST")
 	port := os.Getenv("SHARESECRET_SERVER_PORT")
 
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%s", host, port), grpc.WithInsecure())
+	tlsConfig, err := cmd.NewClientTLSConfigFromEnv()
+	if err != nil {
+		log.Fatalf("fail to load TLS configuration: %v", err)
+	}
+
+	creds := grpc.WithInsecure()
+	if tlsConfig != nil {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	conn, err := grpc.Dial(fmt.Sprintf("%s:%s", host, port), creds)
 	if err != nil {
 		log.Fatalf("fail to dial: %v", err)
 	}
@@ -26,6 +39,11 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
+	// needed to create secrets when the server requires API keys
+	if apiKey := os.Getenv("SHARESECRET_API_KEY"); len(apiKey) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", apiKey)
+	}
+
 	mySecret := "this is a my secret"
 
 	fmt.Println("####### CREATE SECRET #######")
@@ -0,0 +1,463 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/audit"
+	"github.com/bernardosecades/sharesecret/internal/auth"
+	"github.com/bernardosecades/sharesecret/internal/ratelimit"
+	"github.com/bernardosecades/sharesecret/internal/server"
+	"github.com/bernardosecades/sharesecret/internal/storage/bolt"
+	"github.com/bernardosecades/sharesecret/internal/storage/memory"
+	"github.com/bernardosecades/sharesecret/internal/storage/migration"
+	"github.com/bernardosecades/sharesecret/internal/storage/mysql"
+	"github.com/bernardosecades/sharesecret/internal/storage/postgres"
+	"github.com/bernardosecades/sharesecret/internal/storage/redis"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewSecretRepositoryFromEnv returns the repository selected with SHARESECRET_STORAGE:
+// "mysql" (default, see mySQLConfigFromEnv),
+// "postgres" (see postgresConfigFromEnv),
+// "redis" (configured with REDIS_ADDR, localhost:6379 by default, REDIS_PASSWORD and REDIS_DB),
+// "bolt" (file configured with BOLT_PATH, sharesecret.db by default) or "memory"
+func NewSecretRepositoryFromEnv(ctx context.Context) (sharesecret.SecretRepository, error) {
+
+	switch storage := os.Getenv("SHARESECRET_STORAGE"); storage {
+	case "", "mysql":
+		c, err := mySQLConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		return mysql.NewMySQLSecretRepository(ctx, c)
+	case "postgres":
+		c, err := postgresConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		return postgres.NewPostgresSecretRepository(ctx, c)
+	case "redis":
+		addr := os.Getenv("REDIS_ADDR")
+		if len(addr) == 0 {
+			addr = "localhost:6379"
+		}
+
+		db, err := IntFromEnv("REDIS_DB", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		return redis.NewRedisSecretRepository(addr, os.Getenv("REDIS_PASSWORD"), db)
+	case "bolt":
+		path := os.Getenv("BOLT_PATH")
+		if len(path) == 0 {
+			path = "sharesecret.db"
+		}
+
+		return bolt.NewBoltSecretRepository(path)
+	case "memory":
+		return memory.NewMemorySecretRepository(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", storage)
+	}
+}
+
+// PurgeIntervalFromEnv returns how often the server removes the expired secrets itself, SHARESECRET_PURGE_INTERVAL
+// (e.g. "1m", "0" to disable it). By default every minute with the "memory" and "bolt" storages, no other process
+// can purge them while the server is running, and never with the rest (purge them with the purge command).
+func PurgeIntervalFromEnv() (time.Duration, error) {
+
+	def := time.Duration(0)
+	switch os.Getenv("SHARESECRET_STORAGE") {
+	case "memory", "bolt":
+		def = time.Minute
+	}
+
+	return DurationFromEnv("SHARESECRET_PURGE_INTERVAL", def)
+}
+
+// mySQLConfigFromEnv returns the MySQL config from DB_DSN or DB_NAME, DB_USER, DB_PASS, DB_HOST and DB_PORT, DB_TLS,
+// the pool settings DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and DB_CONN_MAX_LIFETIME and the ping retries on startup
+// DB_PING_RETRIES and DB_PING_BACKOFF (10 retries from 1s by default, the pings stop when ctx is done)
+func mySQLConfigFromEnv() (mysql.Config, error) {
+
+	c := mysql.Config{
+		DSN:  os.Getenv("DB_DSN"),
+		Name: os.Getenv("DB_NAME"),
+		User: os.Getenv("DB_USER"),
+		Pass: os.Getenv("DB_PASS"),
+		Host: os.Getenv("DB_HOST"),
+		Port: os.Getenv("DB_PORT"),
+		TLS:  os.Getenv("DB_TLS"),
+	}
+
+	var err error
+	if c.MaxOpenConns, err = IntFromEnv("DB_MAX_OPEN_CONNS", 0); err != nil {
+		return mysql.Config{}, err
+	}
+	if c.MaxIdleConns, err = IntFromEnv("DB_MAX_IDLE_CONNS", 0); err != nil {
+		return mysql.Config{}, err
+	}
+	if c.ConnMaxLifetime, err = DurationFromEnv("DB_CONN_MAX_LIFETIME", 0); err != nil {
+		return mysql.Config{}, err
+	}
+	if c.PingRetries, err = IntFromEnv("DB_PING_RETRIES", 10); err != nil {
+		return mysql.Config{}, err
+	}
+	if c.PingBackoff, err = DurationFromEnv("DB_PING_BACKOFF", time.Second); err != nil {
+		return mysql.Config{}, err
+	}
+
+	return c, nil
+}
+
+// postgresConfigFromEnv returns the PostgreSQL config from DB_NAME, DB_USER, DB_PASS, DB_HOST, DB_PORT and DB_SSL_MODE
+// (disable by default), and the pool settings and ping retries of mySQLConfigFromEnv
+func postgresConfigFromEnv() (postgres.Config, error) {
+
+	c := postgres.Config{
+		Name:    os.Getenv("DB_NAME"),
+		User:    os.Getenv("DB_USER"),
+		Pass:    os.Getenv("DB_PASS"),
+		Host:    os.Getenv("DB_HOST"),
+		Port:    os.Getenv("DB_PORT"),
+		SSLMode: os.Getenv("DB_SSL_MODE"),
+	}
+	if len(c.SSLMode) == 0 {
+		c.SSLMode = "disable"
+	}
+
+	var err error
+	if c.MaxOpenConns, err = IntFromEnv("DB_MAX_OPEN_CONNS", 0); err != nil {
+		return postgres.Config{}, err
+	}
+	if c.MaxIdleConns, err = IntFromEnv("DB_MAX_IDLE_CONNS", 0); err != nil {
+		return postgres.Config{}, err
+	}
+	if c.ConnMaxLifetime, err = DurationFromEnv("DB_CONN_MAX_LIFETIME", 0); err != nil {
+		return postgres.Config{}, err
+	}
+	if c.PingRetries, err = IntFromEnv("DB_PING_RETRIES", 10); err != nil {
+		return postgres.Config{}, err
+	}
+	if c.PingBackoff, err = DurationFromEnv("DB_PING_BACKOFF", time.Second); err != nil {
+		return postgres.Config{}, err
+	}
+
+	return c, nil
+}
+
+// NewMigratorFromEnv returns the migrator of the storage selected with SHARESECRET_STORAGE (configured as in
+// NewSecretRepositoryFromEnv), only "mysql" and "postgres" have migrations
+func NewMigratorFromEnv(ctx context.Context) (*migration.Migrator, error) {
+
+	switch storage := os.Getenv("SHARESECRET_STORAGE"); storage {
+	case "", "mysql":
+		c, err := mySQLConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		return mysql.NewMigrator(ctx, c)
+	case "postgres":
+		c, err := postgresConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		return postgres.NewMigrator(ctx, c)
+	default:
+		return nil, fmt.Errorf("storage %q has no migrations", storage)
+	}
+}
+
+// NewCertificatesFromEnv returns the server certificates configured with SHARESECRET_TLS_CERT_FILE,
+// SHARESECRET_TLS_KEY_FILE and SHARESECRET_TLS_CLIENT_CA_FILE (mutual TLS if it is set, with the client
+// certificate of the REST gateway in SHARESECRET_TLS_GATEWAY_CERT_FILE and SHARESECRET_TLS_GATEWAY_KEY_FILE),
+// nil (without TLS) if no certificate is set
+func NewCertificatesFromEnv() (*server.Certificates, error) {
+
+	certFile := os.Getenv("SHARESECRET_TLS_CERT_FILE")
+	keyFile := os.Getenv("SHARESECRET_TLS_KEY_FILE")
+	clientCAFile := os.Getenv("SHARESECRET_TLS_CLIENT_CA_FILE")
+	if len(certFile) == 0 && len(keyFile) == 0 && len(clientCAFile) == 0 {
+		return nil, nil
+	}
+
+	var opts []server.CertificatesOption
+	gatewayCertFile := os.Getenv("SHARESECRET_TLS_GATEWAY_CERT_FILE")
+	gatewayKeyFile := os.Getenv("SHARESECRET_TLS_GATEWAY_KEY_FILE")
+	if len(gatewayCertFile) > 0 || len(gatewayKeyFile) > 0 {
+		opts = append(opts, server.WithGatewayCertificate(gatewayCertFile, gatewayKeyFile))
+	}
+
+	return server.NewCertificates(certFile, keyFile, clientCAFile, opts...)
+}
+
+// NewClientTLSConfigFromEnv returns the TLS configuration of a client enabled with SHARESECRET_TLS (or setting
+// SHARESECRET_TLS_CA_FILE, the CA verifying the server certificate instead of the system ones) and
+// SHARESECRET_TLS_CLIENT_CERT_FILE and SHARESECRET_TLS_CLIENT_KEY_FILE for mutual TLS, nil if TLS is disabled
+func NewClientTLSConfigFromEnv() (*tls.Config, error) {
+
+	caFile := os.Getenv("SHARESECRET_TLS_CA_FILE")
+	enabled, err := BoolFromEnv("SHARESECRET_TLS", len(caFile) > 0)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	c := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(caFile) > 0 {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("CA file has no PEM certificate")
+		}
+	}
+
+	certFile := os.Getenv("SHARESECRET_TLS_CLIENT_CERT_FILE")
+	keyFile := os.Getenv("SHARESECRET_TLS_CLIENT_KEY_FILE")
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return c, nil
+}
+
+// NewAPIKeyFileFromEnv returns the API keys stored in SHARESECRET_API_KEYS_FILE
+func NewAPIKeyFileFromEnv() (*auth.APIKeyFile, error) {
+
+	path := os.Getenv("SHARESECRET_API_KEYS_FILE")
+	if len(path) == 0 {
+		return nil, errors.New("SHARESECRET_API_KEYS_FILE is not set")
+	}
+
+	return auth.NewAPIKeyFile(path), nil
+}
+
+// NewAuthenticatorFromEnv returns the authenticator of the secret creation accepting the API keys of
+// SHARESECRET_API_KEYS_FILE and the JWTs signed with SHARESECRET_JWT_SECRET (HMAC) or the private key of
+// SHARESECRET_JWT_PUBLIC_KEY_FILE, checking SHARESECRET_JWT_ISSUER and SHARESECRET_JWT_AUDIENCE if they are set.
+// It returns nil (anyone can create secrets) if none is set.
+func NewAuthenticatorFromEnv() (auth.Authenticator, error) {
+
+	var as []auth.Authenticator
+
+	if len(os.Getenv("SHARESECRET_API_KEYS_FILE")) > 0 {
+		keys, err := NewAPIKeyFileFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		as = append(as, keys)
+	}
+
+	issuer := os.Getenv("SHARESECRET_JWT_ISSUER")
+	audience := os.Getenv("SHARESECRET_JWT_AUDIENCE")
+
+	if secret := os.Getenv("SHARESECRET_JWT_SECRET"); len(secret) > 0 {
+		as = append(as, auth.NewHMACJWTVerifier([]byte(secret), issuer, audience))
+	}
+
+	if publicKeyFile := os.Getenv("SHARESECRET_JWT_PUBLIC_KEY_FILE"); len(publicKeyFile) > 0 {
+		publicKey, err := ioutil.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := auth.NewPublicKeyJWTVerifier(publicKey, issuer, audience)
+		if err != nil {
+			return nil, err
+		}
+		as = append(as, v)
+	}
+
+	if len(as) == 0 {
+		return nil, nil
+	}
+
+	return auth.NewAuthenticator(as...), nil
+}
+
+// NewLoggerFromEnv returns a JSON logger writing to stderr from the level SHARESECRET_LOG_LEVEL
+// ("debug", "info" by default, "warn" or "error")
+func NewLoggerFromEnv() (*zap.Logger, error) {
+
+	level := zap.NewAtomicLevel()
+	if l := os.Getenv("SHARESECRET_LOG_LEVEL"); len(l) > 0 {
+		if err := level.UnmarshalText([]byte(l)); err != nil {
+			return nil, err
+		}
+	}
+
+	c := zap.NewProductionConfig()
+	c.Level = level
+	c.EncoderConfig.TimeKey = "time"
+	c.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	return c.Build()
+}
+
+// NewTracerProviderFromEnv returns the tracer provider exporting the spans selected with SHARESECRET_TRACING:
+// "stdout" (JSON), "otlp" (OTLP over gRPC configured with the OTEL_EXPORTER_OTLP_* variables, e.g.
+// OTEL_EXPORTER_OTLP_ENDPOINT) or "off" (default). It returns nil when the tracing is off.
+func NewTracerProviderFromEnv(ctx context.Context) (*sdktrace.TracerProvider, error) {
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch tracing := os.Getenv("SHARESECRET_TRACING"); tracing {
+	case "", "off":
+		return nil, nil
+	case "stdout":
+		exporter, err = stdouttrace.New()
+	case "otlp":
+		exporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", tracing)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String("sharesecret"))),
+	), nil
+}
+
+// NewAuditSinkFromEnv returns the sink of the audit events selected with SHARESECRET_AUDIT_SINK: "off" (default,
+// the secret IDs are not written anywhere), "stdout" (JSON lines), "file" (JSON lines appended to
+// SHARESECRET_AUDIT_FILE, audit.log by default) or "storage" (the audit_event table of r, only mysql and postgres)
+func NewAuditSinkFromEnv(r sharesecret.SecretRepository) (audit.Sink, error) {
+
+	switch sink := os.Getenv("SHARESECRET_AUDIT_SINK"); sink {
+	case "stdout":
+		return audit.NewWriterSink(os.Stdout), nil
+	case "file":
+		path := os.Getenv("SHARESECRET_AUDIT_FILE")
+		if len(path) == 0 {
+			path = "audit.log"
+		}
+		return audit.NewFileSink(path)
+	case "storage":
+		s, ok := r.(audit.Sink)
+		if !ok {
+			return nil, fmt.Errorf("storage %q can not record audit events", os.Getenv("SHARESECRET_STORAGE"))
+		}
+		return s, nil
+	case "", "off":
+		return audit.Discard, nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", sink)
+	}
+}
+
+// BoolFromEnv returns the boolean value of the environment variable key, def if it is not set
+func BoolFromEnv(key string, def bool) (bool, error) {
+
+	v := os.Getenv(key)
+	if len(v) == 0 {
+		return def, nil
+	}
+
+	return strconv.ParseBool(v)
+}
+
+// NewKeyringFromEnv returns the keyring configured with SECRET_KEY (active key), SECRET_KEY_ID (identifier
+// of the active key) and SECRET_RETIRED_KEYS (keys used before a rotation with format "id1:key1,id2:key2")
+func NewKeyringFromEnv() (sharesecret.Keyring, error) {
+
+	secretKey := os.Getenv("SECRET_KEY")
+	secretKeyID := os.Getenv("SECRET_KEY_ID")
+	if len(secretKeyID) == 0 {
+		secretKeyID = sharesecret.DefaultKeyID
+	}
+
+	retiredKeys, err := sharesecret.ParseKeys(os.Getenv("SECRET_RETIRED_KEYS"))
+	if err != nil {
+		return sharesecret.Keyring{}, err
+	}
+
+	return sharesecret.NewKeyring(secretKeyID, secretKey, retiredKeys)
+}
+
+// NewTTLPolicyFromEnv returns the policy of time to live of the secrets configured with SECRET_TTL_MIN,
+// SECRET_TTL_MAX and SECRET_TTL_DEFAULT (durations as "5m", "720h"), sharesecret.DefaultTTLPolicy values if not set
+func NewTTLPolicyFromEnv() (sharesecret.TTLPolicy, error) {
+
+	min, err := DurationFromEnv("SECRET_TTL_MIN", sharesecret.DefaultTTLPolicy.Min)
+	if err != nil {
+		return sharesecret.TTLPolicy{}, err
+	}
+
+	max, err := DurationFromEnv("SECRET_TTL_MAX", sharesecret.DefaultTTLPolicy.Max)
+	if err != nil {
+		return sharesecret.TTLPolicy{}, err
+	}
+
+	def, err := DurationFromEnv("SECRET_TTL_DEFAULT", sharesecret.DefaultTTLPolicy.Default)
+	if err != nil {
+		return sharesecret.TTLPolicy{}, err
+	}
+
+	return sharesecret.NewTTLPolicy(min, max, def)
+}
+
+// DurationFromEnv returns the duration value (e.g. "5m", "720h") of the environment variable key, def if it is not set
+func DurationFromEnv(key string, def time.Duration) (time.Duration, error) {
+
+	v := os.Getenv(key)
+	if len(v) == 0 {
+		return def, nil
+	}
+
+	return time.ParseDuration(v)
+}
+
+// LimitFromEnv returns the rate limit (e.g. "60/1m" or "off") of the environment variable key, def if it is not set
+func LimitFromEnv(key string, def string) (ratelimit.Limit, error) {
+
+	v := os.Getenv(key)
+	if len(v) == 0 {
+		v = def
+	}
+
+	return ratelimit.ParseLimit(v)
+}
+
+// IntFromEnv returns the integer value of the environment variable key, def if it is not set
+func IntFromEnv(key string, def int) (int, error) {
+
+	v := os.Getenv(key)
+	if len(v) == 0 {
+		return def, nil
+	}
+
+	return strconv.Atoi(v)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/bernardosecades/sharesecret/cmd"
+
+	"context"
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = "Usage: migrate up|down|status"
+
+func main() {
+
+	if len(os.Args) != 2 {
+		log.Fatal(usage)
+	}
+
+	migrator, err := cmd.NewMigratorFromEnv(context.Background())
+	if err != nil {
+		log.Fatal("Error to load migrations: ", err)
+	}
+	defer migrator.Close()
+
+	switch os.Args[1] {
+	case "up":
+		migrations, err := migrator.Up()
+		for _, m := range migrations {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("Error to apply migrations: ", err)
+		}
+
+		fmt.Println("Migrations applied:")
+		fmt.Println(len(migrations))
+	case "down":
+		m, err := migrator.Down()
+		if err != nil {
+			log.Fatal("Error to revert migration: ", err)
+		}
+
+		fmt.Printf("Reverted %04d_%s\n", m.Version, m.Name)
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			log.Fatal("Error to get migrations status: ", err)
+		}
+
+		for _, s := range status {
+			appliedAt := "pending"
+			if !s.AppliedAt.IsZero() {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+	default:
+		log.Fatal(usage)
+	}
+}
@@ -1,24 +1,42 @@
 package main
 
 import (
-	_ "github.com/bernardosecades/sharesecret/cmd"
-	"github.com/bernardosecades/sharesecret/internal/storage/mysql"
+	"github.com/bernardosecades/sharesecret/cmd"
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/metrics"
 
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
 )
 
 func main() {
 
-	dbName := os.Getenv("DB_NAME")
-	dbPass := os.Getenv("DB_PASS")
-	dbUser := os.Getenv("DB_USER")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
+	secretRepository, err := cmd.NewSecretRepositoryFromEnv(context.Background())
+	if err != nil {
+		log.Fatal("Error to load storage: ", err)
+	}
 
-	secretRepository := mysql.NewMySQLSecretRepository(dbName, dbUser, dbPass, dbHost, dbPort)
-	r, err := secretRepository.RemoveSecretsExpired()
+	if c, ok := secretRepository.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	auditSink, err := cmd.NewAuditSinkFromEnv(secretRepository)
+	if err != nil {
+		log.Fatal("Error to load audit sink: ", err)
+	}
+
+	if c, ok := auditSink.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	r, err := sharesecret.PurgeSecretsExpired(context.Background(), secretRepository, auditSink)
 
 	if err != nil {
 		log.Fatal("Error to try to remove expired secrets", err)
@@ -26,4 +44,18 @@ func main() {
 
 	fmt.Println("Secrets deleted:")
 	fmt.Println(r)
+
+	reg := metrics.NewPurgeRegistry(r, time.Now())
+
+	if url := os.Getenv("SHARESECRET_PUSHGATEWAY_URL"); len(url) > 0 {
+		if err := push.New(url, "sharesecret_purge").Gatherer(reg).Push(); err != nil {
+			log.Fatal("Error to push the metrics: ", err)
+		}
+	}
+
+	if path := os.Getenv("SHARESECRET_METRICS_TEXTFILE"); len(path) > 0 {
+		if err := prometheus.WriteToTextfile(path, reg); err != nil {
+			log.Fatal("Error to write the metrics: ", err)
+		}
+	}
 }
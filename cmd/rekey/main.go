@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/bernardosecades/sharesecret/cmd"
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+func main() {
+
+	secretPassword := os.Getenv("SECRET_PASSWORD")
+
+	keyring, err := cmd.NewKeyringFromEnv()
+	if err != nil {
+		log.Fatal("Error to load secret keys: ", err)
+	}
+
+	storageTimeout, err := cmd.DurationFromEnv("SHARESECRET_STORAGE_TIMEOUT", 0)
+	if err != nil {
+		log.Fatal("Error to load storage timeout: ", err)
+	}
+
+	secretRepository, err := cmd.NewSecretRepositoryFromEnv(context.Background())
+	if err != nil {
+		log.Fatal("Error to load storage: ", err)
+	}
+
+	if c, ok := secretRepository.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	secretService := sharesecret.NewSecretServiceWithKeyring(secretRepository, keyring, secretPassword, sharesecret.WithStorageTimeout(storageTimeout))
+	r, err := secretService.RekeySecrets(context.Background())
+
+	if err != nil {
+		log.Fatal("Error to try to rekey secrets", err)
+	}
+
+	fmt.Println("Secrets rekeyed:")
+	fmt.Println(r.Rekeyed)
+
+	if r.Skipped > 0 {
+		fmt.Println("Secrets with password that can not be rekeyed, keep the retired keys until they expire:")
+		fmt.Println(r.Skipped)
+	}
+}
@@ -2,59 +2,316 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"io"
 	"log"
+	"net"
+	nethttp "net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
-	_ "github.com/bernardosecades/sharesecret/cmd"
+	"github.com/bernardosecades/sharesecret/cmd"
 	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/audit"
+	"github.com/bernardosecades/sharesecret/internal/metrics"
+	"github.com/bernardosecades/sharesecret/internal/ratelimit"
 	"github.com/bernardosecades/sharesecret/internal/server"
 	"github.com/bernardosecades/sharesecret/internal/server/grpc"
 	"github.com/bernardosecades/sharesecret/internal/server/http"
-	"github.com/bernardosecades/sharesecret/internal/storage/mysql"
+	"github.com/bernardosecades/sharesecret/internal/server/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapgrpc"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/grpclog"
 )
 
 func main() {
 
+	logger, err := cmd.NewLoggerFromEnv()
+	if err != nil {
+		log.Fatal("Error to load logger: ", err)
+	}
+	defer logger.Sync()
+
+	// the standard logger (used by the packages of the server) and the gRPC logger log JSON too,
+	// the gRPC logger has to be set before gRPC logs anything
+	defer zap.RedirectStdLog(logger)()
+	grpclog.SetLoggerV2(zapgrpc.NewLogger(logger))
+
 	protocol := os.Getenv("SHARESECRET_SERVER_PROTOCOL")
 	host := os.Getenv("SHARESECRET_SERVER_HOST")
 	port := os.Getenv("SHARESECRET_SERVER_PORT")
 
-	dbName := os.Getenv("DB_NAME")
-	dbPass := os.Getenv("DB_PASS")
-	dbUser := os.Getenv("DB_USER")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
+	httpHost := os.Getenv("SHARESECRET_HTTP_HOST")
+	httpPort := os.Getenv("SHARESECRET_HTTP_PORT")
+	if len(httpPort) == 0 {
+		httpPort = "8080"
+	}
 
-	secretKey := os.Getenv("SECRET_KEY")
 	secretPassword := os.Getenv("SECRET_PASSWORD")
 
-	secretRepository := mysql.NewMySQLSecretRepository(dbName, dbUser, dbPass, dbHost, dbPort)
-	secretService := sharesecret.NewSecretService(secretRepository, secretKey, secretPassword)
-
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
+	// the gateway keeps its connection to the gRPC server until the end to forward the requests in flight
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// SIGINT (ctrl+c) and SIGTERM (docker stop) start the shutdown, or stop waiting for the storage on startup
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	keyring, err := cmd.NewKeyringFromEnv()
+	if err != nil {
+		log.Fatal("Error to load secret keys: ", err)
+	}
+
+	ttlPolicy, err := cmd.NewTTLPolicyFromEnv()
+	if err != nil {
+		log.Fatal("Error to load time to live policy: ", err)
+	}
+
+	maxPasswordAttempts, err := cmd.IntFromEnv("SECRET_MAX_PASSWORD_ATTEMPTS", sharesecret.DefaultMaxPasswordAttempts)
+	if err != nil {
+		log.Fatal("Error to load max password attempts: ", err)
+	}
+
+	maxConcurrentDerivations, err := cmd.IntFromEnv("SHARESECRET_MAX_CONCURRENT_DERIVATIONS", sharesecret.DefaultMaxConcurrentDerivations)
+	if err != nil {
+		log.Fatal("Error to load max concurrent derivations: ", err)
+	}
+
+	autoMigrate, err := cmd.BoolFromEnv("SHARESECRET_AUTO_MIGRATE", false)
+	if err != nil {
+		log.Fatal("Error to load auto migrate: ", err)
+	}
+
+	if autoMigrate {
+		migrator, err := cmd.NewMigratorFromEnv(signalCtx)
+		if err != nil {
+			log.Fatal("Error to load migrations: ", err)
+		}
+
+		migrations, err := migrator.Up()
+		migrator.Close()
+		if err != nil {
+			log.Fatal("Error to apply migrations: ", err)
+		}
+
+		log.Printf("Migrations applied: %d\n", len(migrations))
+	}
+
+	storageTimeout, err := cmd.DurationFromEnv("SHARESECRET_STORAGE_TIMEOUT", 0)
+	if err != nil {
+		log.Fatal("Error to load storage timeout: ", err)
+	}
+
+	secretRepository, err := cmd.NewSecretRepositoryFromEnv(signalCtx)
+	if err != nil {
+		log.Fatal("Error to load storage: ", err)
+	}
+
+	purgeInterval, err := cmd.PurgeIntervalFromEnv()
+	if err != nil {
+		log.Fatal("Error to load purge interval: ", err)
+	}
+
+	tracerProvider, err := cmd.NewTracerProviderFromEnv(context.Background())
+	if err != nil {
+		log.Fatal("Error to load tracing: ", err)
+	}
+
+	// the trace context of the requests is propagated even when they are not traced here
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if tracerProvider != nil {
+		otel.SetTracerProvider(tracerProvider)
+	}
+
+	auditSink, err := cmd.NewAuditSinkFromEnv(secretRepository)
+	if err != nil {
+		log.Fatal("Error to load audit sink: ", err)
+	}
+
+	metricsEnabled, err := cmd.BoolFromEnv("SHARESECRET_METRICS", true)
+	if err != nil {
+		log.Fatal("Error to load metrics: ", err)
+	}
+
+	// the metrics of the secrets are counted from the audit events
+	var metricsHandler nethttp.Handler
+	if metricsEnabled {
+		prometheus.MustRegister(metrics.NewSecretsCollector(secretRepository, 5*time.Second))
+		auditSink = audit.NewMultiSink(auditSink, metrics.NewSink(prometheus.DefaultRegisterer))
+		metricsHandler = metrics.Handler(prometheus.DefaultGatherer)
+	}
+
+	secretService := sharesecret.NewSecretServiceWithKeyring(
+		secretRepository,
+		keyring,
+		secretPassword,
+		sharesecret.WithTTLPolicy(ttlPolicy),
+		sharesecret.WithMaxPasswordAttempts(maxPasswordAttempts),
+		sharesecret.WithMaxConcurrentDerivations(maxConcurrentDerivations),
+		sharesecret.WithStorageTimeout(storageTimeout),
+		sharesecret.WithAuditSink(auditSink),
+	)
+	if tracerProvider != nil {
+		secretService = sharesecret.NewTracedSecretService(secretService)
+	}
+
+	singlePort, err := cmd.BoolFromEnv("SHARESECRET_SINGLE_PORT", false)
+	if err != nil {
+		log.Fatal("Error to load single port: ", err)
+	}
+
+	var allowedOrigins []string
+	if origins := os.Getenv("SHARESECRET_ALLOWED_ORIGINS"); len(origins) > 0 {
+		allowedOrigins = strings.Split(origins, ",")
+	}
+
+	certs, err := cmd.NewCertificatesFromEnv()
+	if err != nil {
+		log.Fatal("Error to load TLS certificates: ", err)
+	}
+
+	authenticator, err := cmd.NewAuthenticatorFromEnv()
+	if err != nil {
+		log.Fatal("Error to load authentication: ", err)
+	}
+
+	// off by default, behind a proxy not listed in SHARESECRET_TRUSTED_PROXIES all the clients would share its IP
+	ipLimit, err := cmd.LimitFromEnv("SHARESECRET_RATE_LIMIT_IP", "off")
+	if err != nil {
+		log.Fatal("Error to load rate limit by IP: ", err)
+	}
+
+	secretLimit, err := cmd.LimitFromEnv("SHARESECRET_RATE_LIMIT_SECRET", "10/1m")
+	if err != nil {
+		log.Fatal("Error to load rate limit by secret: ", err)
+	}
+
+	trustedProxies, err := grpc.ParseTrustedProxies(os.Getenv("SHARESECRET_TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatal("Error to load trusted proxies: ", err)
+	}
+
+	shutdownTimeout, err := cmd.DurationFromEnv("SHARESECRET_SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		log.Fatal("Error to load shutdown timeout: ", err)
+	}
+
+	srvCfg := server.Config{Protocol: protocol, Host: host, Port: port, TLS: certs, AllowedOrigins: allowedOrigins, MetricsHandler: metricsHandler}
+	if tracerProvider != nil {
+		// first so the other interceptors (e.g. the logs with the trace ID) are in the span of the call
+		srvCfg.UnaryInterceptors = append(srvCfg.UnaryInterceptors, otelgrpc.UnaryServerInterceptor())
+	}
+	srvCfg.UnaryInterceptors = append(srvCfg.UnaryInterceptors, grpc.LoggingInterceptor(logger))
+	if metricsEnabled {
+		srvCfg.UnaryInterceptors = append(srvCfg.UnaryInterceptors, grpc.MetricsInterceptor(prometheus.DefaultRegisterer))
+	}
+
+	// the rate limits go before the authentication so the calls rejected by them do not try to authenticate
+	if ipLimit.Enabled() || secretLimit.Enabled() {
+		// a bucket idle for the longest period is full, removing it does not change the limits
+		idle := ipLimit.Period()
+		if secretLimit.Period() > idle {
+			idle = secretLimit.Period()
+		}
+		store := ratelimit.NewMemoryStore(idle)
+		srvCfg.UnaryInterceptors = append(srvCfg.UnaryInterceptors, grpc.RateLimitInterceptor(store, ipLimit, secretLimit, trustedProxies))
+		log.Printf("Rate limits: %s by IP and %s by secret\n", ipLimit, secretLimit)
+	}
+
+	if authenticator != nil {
+		srvCfg.UnaryInterceptors = append(srvCfg.UnaryInterceptors, grpc.AuthInterceptor(authenticator, grpc.CreateSecretMethod))
+	} else {
+		log.Println("No API keys nor JWT configured, anyone can create secrets")
+	}
+
+	var srv server.Server
+	var httpSrv *http.Server
+	httpAddr := net.JoinHostPort(httpHost, httpPort)
+	if singlePort {
+		srv = mux.NewServer(srvCfg, secretService)
+	} else {
+		srv = grpc.NewServer(srvCfg, secretService)
+		httpSrv = http.NewServer(httpAddr, srvCfg.Endpoint(), certs, metricsHandler)
+	}
+
 	// group context: https://bionic.fullstory.com/why-you-should-be-using-errgroup-withcontext-in-golang-server-handlers/
-	g, ctx := errgroup.WithContext(ctx)
+	g, groupCtx := errgroup.WithContext(signalCtx)
 
 	g.Go(func() error {
-		srvCfg := server.Config{Protocol: protocol, Host: host, Port: port}
-		srv := grpc.NewServer(srvCfg, secretService)
-
-		log.Printf("gRPC server running at %s://%s:%s ...\n", protocol, host, port)
+		if singlePort {
+			log.Printf("gRPC, gRPC-Web and HTTP server running at %s://%s:%s ...\n", protocol, host, port)
+		} else {
+			log.Printf("gRPC server running at %s://%s:%s ...\n", protocol, host, port)
+		}
 		return srv.Serve()
 	})
 
+	if httpSrv != nil {
+		g.Go(func() error {
+			log.Printf("HTTP server running at %s ...\n", httpAddr)
+			return httpSrv.Serve(ctx)
+		})
+	}
+
+	// stopped with the servers, before the repository is closed
+	if purgeInterval > 0 {
+		g.Go(func() error {
+			log.Printf("Removing the expired secrets every %s ...\n", purgeInterval)
+			sharesecret.RunPurge(groupCtx, secretRepository, auditSink, purgeInterval)
+			return nil
+		})
+	}
+
+	// on a signal or when a server fails the others are stopped
 	g.Go(func() error {
-		httpAddr := fmt.Sprintf(":%s", port)
-		httpSrv := http.NewServer(httpAddr)
+		<-groupCtx.Done()
+		log.Printf("Shutting down, waiting up to %s for the requests in flight ...\n", shutdownTimeout)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		// the gateway first, its requests in flight are calls to the gRPC server
+		var httpErr error
+		if httpSrv != nil {
+			httpErr = httpSrv.Shutdown(shutdownCtx)
+		}
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
 
-		log.Printf("HTTP server running at %s ...\n", httpAddr)
-		return httpSrv.Serve(ctx)
+		return httpErr
 	})
 
-	log.Fatal(g.Wait())
+	err = g.Wait()
+
+	// the spans of the last requests are exported before exiting
+	if tracerProvider != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			log.Println("Error to export the last spans: ", err)
+		}
+		cancel()
+	}
+
+	// the "storage" audit sink is the repository, closing it twice does nothing
+	if c, ok := auditSink.(io.Closer); ok {
+		c.Close()
+	}
+
+	if c, ok := secretRepository.(io.Closer); ok {
+		c.Close()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Server stopped")
 }
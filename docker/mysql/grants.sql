@@ -0,0 +1,2 @@
+-- the integration tests upgrading the baseline schema create databases of their own
+GRANT ALL PRIVILEGES ON `sharesecret\_%`.* TO 'berni'@'%';
@@ -20,6 +20,8 @@ const _ = grpc.SupportPackageIsVersion7
 type SecretServiceClient interface {
 	CreateSecret(ctx context.Context, in *CreateSecretRequest, opts ...grpc.CallOption) (*CreateSecretResponse, error)
 	SeeSecret(ctx context.Context, in *SeeSecretRequest, opts ...grpc.CallOption) (*SeeSecretResponse, error)
+	GetSecretInfo(ctx context.Context, in *GetSecretInfoRequest, opts ...grpc.CallOption) (*GetSecretInfoResponse, error)
+	DeleteSecret(ctx context.Context, in *DeleteSecretRequest, opts ...grpc.CallOption) (*DeleteSecretResponse, error)
 }
 
 type secretServiceClient struct {
@@ -48,12 +50,32 @@ func (c *secretServiceClient) SeeSecret(ctx context.Context, in *SeeSecretReques
 	return out, nil
 }
 
+func (c *secretServiceClient) GetSecretInfo(ctx context.Context, in *GetSecretInfoRequest, opts ...grpc.CallOption) (*GetSecretInfoResponse, error) {
+	out := new(GetSecretInfoResponse)
+	err := c.cc.Invoke(ctx, "/sharesecret.SecretService/GetSecretInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretServiceClient) DeleteSecret(ctx context.Context, in *DeleteSecretRequest, opts ...grpc.CallOption) (*DeleteSecretResponse, error) {
+	out := new(DeleteSecretResponse)
+	err := c.cc.Invoke(ctx, "/sharesecret.SecretService/DeleteSecret", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SecretServiceServer is the server API for SecretService service.
 // All implementations should embed UnimplementedSecretServiceServer
 // for forward compatibility
 type SecretServiceServer interface {
 	CreateSecret(context.Context, *CreateSecretRequest) (*CreateSecretResponse, error)
 	SeeSecret(context.Context, *SeeSecretRequest) (*SeeSecretResponse, error)
+	GetSecretInfo(context.Context, *GetSecretInfoRequest) (*GetSecretInfoResponse, error)
+	DeleteSecret(context.Context, *DeleteSecretRequest) (*DeleteSecretResponse, error)
 }
 
 // UnimplementedSecretServiceServer should be embedded to have forward compatible implementations.
@@ -66,6 +88,12 @@ func (UnimplementedSecretServiceServer) CreateSecret(context.Context, *CreateSec
 func (UnimplementedSecretServiceServer) SeeSecret(context.Context, *SeeSecretRequest) (*SeeSecretResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SeeSecret not implemented")
 }
+func (UnimplementedSecretServiceServer) GetSecretInfo(context.Context, *GetSecretInfoRequest) (*GetSecretInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSecretInfo not implemented")
+}
+func (UnimplementedSecretServiceServer) DeleteSecret(context.Context, *DeleteSecretRequest) (*DeleteSecretResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSecret not implemented")
+}
 
 // UnsafeSecretServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to SecretServiceServer will
@@ -114,6 +142,42 @@ func _SecretService_SeeSecret_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SecretService_GetSecretInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSecretInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServiceServer).GetSecretInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sharesecret.SecretService/GetSecretInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServiceServer).GetSecretInfo(ctx, req.(*GetSecretInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecretService_DeleteSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServiceServer).DeleteSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sharesecret.SecretService/DeleteSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServiceServer).DeleteSecret(ctx, req.(*DeleteSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // SecretService_ServiceDesc is the grpc.ServiceDesc for SecretService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -129,6 +193,14 @@ var SecretService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SeeSecret",
 			Handler:    _SecretService_SeeSecret_Handler,
 		},
+		{
+			MethodName: "GetSecretInfo",
+			Handler:    _SecretService_GetSecretInfo_Handler,
+		},
+		{
+			MethodName: "DeleteSecret",
+			Handler:    _SecretService_DeleteSecret_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "secret.proto",
@@ -0,0 +1,143 @@
+// Package audit records the lifecycle events of the secrets (created, viewed, deleted...) in a sink.
+//
+// The events never have the content nor the password of the secrets.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventType is what happened to a secret
+type EventType string
+
+// All the events of the lifecycle of a secret
+const (
+	// EventCreated a secret was created
+	EventCreated EventType = "created"
+	// EventViewed a view of a secret was consumed, it is removed after its last view
+	EventViewed EventType = "viewed"
+	// EventWrongPassword a wrong password was used to see a secret
+	EventWrongPassword EventType = "wrong_password"
+	// EventLockedOut a secret was removed after too many wrong passwords
+	EventLockedOut EventType = "locked_out"
+	// EventDeleted a secret was deleted with its deletion token
+	EventDeleted EventType = "deleted"
+	// EventExpired an expired secret was removed by the purge
+	EventExpired EventType = "expired"
+	// EventPurged the purge removed the expired secrets, the event has how many instead of a secret ID
+	EventPurged EventType = "purged"
+)
+
+// Event is something that happened to the secret SecretID (or Count secrets) at Time
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     EventType `json:"event"`
+	SecretID string    `json:"secret_id,omitempty"`
+	Count    int64     `json:"count,omitempty"`
+}
+
+// NewEvent returns an event of the secret id that happens now
+func NewEvent(t EventType, id string) Event {
+	return Event{Time: time.Now().UTC(), Type: t, SecretID: id}
+}
+
+// Sink records the events, e.g. in a file or in a table of the storage of the secrets
+type Sink interface {
+	Record(ctx context.Context, e Event) error
+}
+
+type discard struct{}
+
+// Discard is a sink that records nothing
+var Discard Sink = discard{}
+
+func (discard) Record(context.Context, Event) error {
+	return nil
+}
+
+type multiSink []Sink
+
+// NewMultiSink returns a sink recording the events in every sink, the first error is returned once all of them
+// have recorded the event
+func NewMultiSink(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+func (s multiSink) Record(ctx context.Context, e Event) error {
+
+	var err error
+	for _, sink := range s {
+		if sinkErr := sink.Record(ctx, e); sinkErr != nil && err == nil {
+			err = sinkErr
+		}
+	}
+
+	return err
+}
+
+// Close closes the sinks that are an io.Closer
+func (s multiSink) Close() error {
+
+	var err error
+	for _, sink := range s {
+		if c, ok := sink.(io.Closer); ok {
+			if closeErr := c.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}
+
+	return err
+}
+
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a sink writing the events to w as JSON lines (e.g. os.Stdout)
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+// NewFileSink returns a sink appending the events to the file at path as JSON lines, the file is created if
+// it does not exist and closed with Close
+func NewFileSink(path string) (Sink, error) {
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writerSink{w: f}, nil
+}
+
+func (s *writerSink) Record(_ context.Context, e Event) error {
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(b, '\n'))
+
+	return err
+}
+
+// Close closes the writer of the sink if it is an io.Closer (e.g. the file of NewFileSink)
+func (s *writerSink) Close() error {
+
+	if c, ok := s.w.(io.Closer); ok && s.w != os.Stdout && s.w != os.Stderr {
+		return c.Close()
+	}
+
+	return nil
+}
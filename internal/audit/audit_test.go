@@ -0,0 +1,61 @@
+// +build unit
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterSinkWritesJSONLines(t *testing.T) {
+
+	var b bytes.Buffer
+	s := NewWriterSink(&b)
+
+	tm := time.Date(2021, 3, 15, 10, 0, 0, 0, time.UTC)
+	assert.Nil(t, s.Record(context.Background(), Event{Time: tm, Type: EventCreated, SecretID: "727d7040-aac7-4dc3-ab44-938bfba92ebd"}))
+	assert.Nil(t, s.Record(context.Background(), Event{Time: tm, Type: EventPurged, Count: 3}))
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+
+	if assert.Len(t, lines, 2) {
+		assert.JSONEq(t, `{"time": "2021-03-15T10:00:00Z", "event": "created", "secret_id": "727d7040-aac7-4dc3-ab44-938bfba92ebd"}`, lines[0])
+		assert.JSONEq(t, `{"time": "2021-03-15T10:00:00Z", "event": "purged", "count": 3}`, lines[1])
+	}
+}
+
+func TestFileSinkAppendsEvents(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for i := 0; i < 2; i++ {
+		s, err := NewFileSink(path)
+		if err != nil {
+			t.Fatalf("NewFileSink failed: %v", err)
+		}
+
+		assert.Nil(t, s.Record(context.Background(), NewEvent(EventViewed, "727d7040-aac7-4dc3-ab44-938bfba92ebd")))
+		assert.Nil(t, s.(interface{ Close() error }).Close())
+	}
+
+	b, err := ioutil.ReadFile(path)
+
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+
+	if assert.Len(t, lines, 2) {
+		var e Event
+		assert.Nil(t, json.Unmarshal([]byte(lines[1]), &e))
+		assert.Equal(t, EventViewed, e.Type)
+		assert.Equal(t, "727d7040-aac7-4dc3-ab44-938bfba92ebd", e.SecretID)
+	}
+}
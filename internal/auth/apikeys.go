@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, it tells them apart from JWTs
+const APIKeyPrefix = "ssk_"
+
+// All errors reported by the API keys
+var (
+	ErrInvalidKeyName = errors.New("API key name should have between 1 and 64 letters, digits, '.', '_' or '-'")
+	ErrDuplicateKey   = errors.New("API key name already exists")
+	ErrKeyNotFound    = errors.New("API key not found")
+)
+
+var keyNameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// APIKey is a stored API key, only the SHA-256 of the key is stored
+type APIKey struct {
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIKeyFile stores the API keys in a JSON file. The file is read again when it changes so the keys
+// created or revoked (e.g. with the apikey command) are used without a restart.
+type APIKeyFile struct {
+	path string
+
+	mu     sync.Mutex
+	sum    [sha256.Size]byte
+	byHash map[string]APIKey
+}
+
+// NewAPIKeyFile returns the API keys stored in path, the file is created with the first key
+func NewAPIKeyFile(path string) *APIKeyFile {
+	return &APIKeyFile{path: path}
+}
+
+// Create stores a new API key with name and returns the key, it can't be recovered later
+func (f *APIKeyFile) Create(name string) (string, error) {
+
+	if !keyNameRegexp.MatchString(name) {
+		return "", ErrInvalidKeyName
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys, err := f.read()
+	if err != nil {
+		return "", err
+	}
+
+	for _, k := range keys {
+		if k.Name == name {
+			return "", ErrDuplicateKey
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	keys = append(keys, APIKey{Name: name, Hash: hashKey(key), CreatedAt: time.Now().UTC()})
+	if err := f.write(keys); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// Revoke removes the API key with name
+func (f *APIKeyFile) Revoke(name string) error {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	for i, k := range keys {
+		if k.Name == name {
+			return f.write(append(keys[:i], keys[i+1:]...))
+		}
+	}
+
+	return ErrKeyNotFound
+}
+
+// List returns the API keys sorted by name
+func (f *APIKeyFile) List() ([]APIKey, error) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+
+	return keys, nil
+}
+
+// Authenticate returns the principal of the API key
+func (f *APIKeyFile) Authenticate(key string) (Principal, error) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.reload()
+
+	k, ok := f.byHash[hashKey(key)]
+	if !ok {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	return Principal{Name: k.Name, Method: MethodAPIKey}, nil
+}
+
+// reload parses the file again if its content changed since the last read, the modification time is not enough
+// because a revocation within its resolution would be missed. The previous keys are kept until the next change
+// if it can't be read.
+func (f *APIKeyFile) reload() {
+
+	content, err := f.content()
+	if err != nil {
+		f.keepPrevious(err)
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	if f.byHash != nil && sum == f.sum {
+		return
+	}
+	// a content that can not be parsed is reported once, not on every call
+	f.sum = sum
+
+	keys, err := parseKeys(content)
+	if err != nil {
+		f.keepPrevious(err)
+		return
+	}
+
+	f.byHash = make(map[string]APIKey, len(keys))
+	for _, k := range keys {
+		f.byHash[k.Hash] = k
+	}
+}
+
+func (f *APIKeyFile) keepPrevious(err error) {
+
+	log.Printf("Error to reload API keys, keeping the previous ones: %v\n", err)
+	if f.byHash == nil {
+		f.byHash = make(map[string]APIKey)
+	}
+}
+
+func (f *APIKeyFile) read() ([]APIKey, error) {
+
+	content, err := f.content()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseKeys(content)
+}
+
+// content returns the content of the file, empty if it does not exist yet
+func (f *APIKeyFile) content() ([]byte, error) {
+
+	content, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return content, err
+}
+
+func parseKeys(content []byte) ([]APIKey, error) {
+
+	if len(content) == 0 {
+		return nil, nil
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// write replaces the file so a server reading it never sees it half written
+func (f *APIKeyFile) write(keys []APIKey) error {
+
+	content, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func hashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,142 @@
+// +build unit
+
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyFileCreateAndAuthenticate(t *testing.T) {
+
+	f := NewAPIKeyFile(filepath.Join(t.TempDir(), "api_keys.json"))
+
+	key, err := f.Create("frontend")
+
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix))
+
+	p, err := f.Authenticate(key)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Principal{Name: "frontend", Method: MethodAPIKey}, p)
+
+	_, err = f.Authenticate(key + "x")
+
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func TestAPIKeyFileStoresOnlyTheHash(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	f := NewAPIKeyFile(path)
+
+	key, _ := f.Create("frontend")
+	content, err := ioutil.ReadFile(path)
+
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), key)
+	assert.Contains(t, string(content), hashKey(key))
+}
+
+func TestAPIKeyFileCreateInvalidOrDuplicateName(t *testing.T) {
+
+	f := NewAPIKeyFile(filepath.Join(t.TempDir(), "api_keys.json"))
+
+	_, err1 := f.Create("")
+	_, err2 := f.Create("my key")
+	_, err3 := f.Create("frontend")
+	_, err4 := f.Create("frontend")
+
+	assert.Equal(t, ErrInvalidKeyName, err1)
+	assert.Equal(t, ErrInvalidKeyName, err2)
+	assert.Nil(t, err3)
+	assert.Equal(t, ErrDuplicateKey, err4)
+}
+
+func TestAPIKeyFileListAndRevoke(t *testing.T) {
+
+	f := NewAPIKeyFile(filepath.Join(t.TempDir(), "api_keys.json"))
+
+	_, _ = f.Create("zeta")
+	key, _ := f.Create("alpha")
+
+	list, err := f.List()
+
+	assert.Nil(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "alpha", list[0].Name)
+		assert.Equal(t, "zeta", list[1].Name)
+	}
+
+	assert.Nil(t, f.Revoke("alpha"))
+	assert.Equal(t, ErrKeyNotFound, f.Revoke("alpha"))
+
+	_, err = f.Authenticate(key)
+
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func TestAPIKeyFileReloadsKeysChangedByOtherProcess(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	server := NewAPIKeyFile(path)
+	admin := NewAPIKeyFile(path)
+
+	_, err := server.Authenticate(APIKeyPrefix + "unknown")
+
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	key, _ := admin.Create("frontend")
+	// the modification time can have a coarse resolution
+	future := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(path, future, future))
+
+	p, err := server.Authenticate(key)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "frontend", p.Name)
+
+	assert.Nil(t, admin.Revoke("frontend"))
+	future = future.Add(time.Minute)
+	assert.Nil(t, os.Chtimes(path, future, future))
+
+	_, err = server.Authenticate(key)
+
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func TestAPIKeyFileReloadsKeysChangedWithTheSameModificationTimeAndSize(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	server := NewAPIKeyFile(path)
+	admin := NewAPIKeyFile(path)
+
+	key, _ := admin.Create("frontend")
+	fi, _ := os.Stat(path)
+
+	_, err := server.Authenticate(key)
+
+	assert.Nil(t, err)
+
+	// the key is replaced by another one within the resolution of the modification time
+	content, _ := ioutil.ReadFile(path)
+	other := strings.Replace(string(content), hashKey(key), hashKey(APIKeyPrefix+"other"), 1)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(other), 0600))
+	assert.Nil(t, os.Chtimes(path, fi.ModTime(), fi.ModTime()))
+
+	_, err = server.Authenticate(key)
+
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	p, err := server.Authenticate(APIKeyPrefix + "other")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "frontend", p.Name)
+}
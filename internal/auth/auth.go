@@ -0,0 +1,66 @@
+// Package auth authenticates the callers of the service with API keys or JWTs.
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// All errors reported by the authenticators
+var (
+	ErrMissingCredentials = errors.New("missing credentials, use an API key or a bearer token")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authentication methods of a Principal
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is an authenticated caller
+type Principal struct {
+	// Name is the name of the API key or the subject of the JWT
+	Name   string
+	Method string
+}
+
+// Authenticator validates the credential sent by a caller
+type Authenticator interface {
+	Authenticate(credential string) (Principal, error)
+}
+
+type authenticators []Authenticator
+
+// NewAuthenticator returns an authenticator accepting the credentials accepted by any of as
+func NewAuthenticator(as ...Authenticator) Authenticator {
+	return authenticators(as)
+}
+
+func (as authenticators) Authenticate(credential string) (Principal, error) {
+
+	if len(credential) == 0 {
+		return Principal{}, ErrMissingCredentials
+	}
+
+	for _, a := range as {
+		if p, err := a.Authenticate(credential); err == nil {
+			return p, nil
+		}
+	}
+
+	return Principal{}, ErrInvalidCredentials
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx with the authenticated caller
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the authenticated caller of ctx, false if the caller is anonymous
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
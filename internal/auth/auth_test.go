@@ -0,0 +1,50 @@
+// +build unit
+
+package auth
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticatorAcceptsAPIKeysAndJWTs(t *testing.T) {
+
+	keys := NewAPIKeyFile(filepath.Join(t.TempDir(), "api_keys.json"))
+	key, _ := keys.Create("frontend")
+	a := NewAuthenticator(keys, NewHMACJWTVerifier(jwtSecret, "", ""))
+
+	p1, err1 := a.Authenticate(key)
+
+	assert.Nil(t, err1)
+	assert.Equal(t, Principal{Name: "frontend", Method: MethodAPIKey}, p1)
+
+	p2, err2 := a.Authenticate(sign(t, jwt.SigningMethodHS256, jwtSecret, jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}))
+
+	assert.Nil(t, err2)
+	assert.Equal(t, Principal{Name: "alice", Method: MethodJWT}, p2)
+
+	_, err3 := a.Authenticate("")
+
+	assert.Equal(t, ErrMissingCredentials, err3)
+
+	_, err4 := a.Authenticate(APIKeyPrefix + "unknown")
+
+	assert.Equal(t, ErrInvalidCredentials, err4)
+}
+
+func TestContext(t *testing.T) {
+
+	_, ok1 := FromContext(context.Background())
+
+	assert.False(t, ok1)
+
+	p, ok2 := FromContext(NewContext(context.Background(), Principal{Name: "frontend", Method: MethodAPIKey}))
+
+	assert.True(t, ok2)
+	assert.Equal(t, "frontend", p.Name)
+}
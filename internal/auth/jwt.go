@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidPublicKey is returned when the public key is not a PEM RSA, ECDSA or Ed25519 key
+var ErrInvalidPublicKey = errors.New("public key should be a PEM RSA, ECDSA or Ed25519 key")
+
+// JWTVerifier authenticates bearer JWTs signed by the issuer, they need an expiration time
+type JWTVerifier struct {
+	key      interface{}
+	parser   *jwt.Parser
+	issuer   string
+	audience string
+}
+
+// NewHMACJWTVerifier returns a verifier of the JWTs signed with secret (HS256, HS384 or HS512),
+// issuer and audience are checked when they are not empty
+func NewHMACJWTVerifier(secret []byte, issuer string, audience string) *JWTVerifier {
+	return &JWTVerifier{
+		key:      secret,
+		parser:   &jwt.Parser{ValidMethods: []string{"HS256", "HS384", "HS512"}},
+		issuer:   issuer,
+		audience: audience,
+	}
+}
+
+// NewPublicKeyJWTVerifier returns a verifier of the JWTs signed with the private key of publicKeyPEM,
+// issuer and audience are checked when they are not empty
+func NewPublicKeyJWTVerifier(publicKeyPEM []byte, issuer string, audience string) (*JWTVerifier, error) {
+
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	var methods []string
+	switch key.(type) {
+	case *rsa.PublicKey:
+		methods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		methods = []string{"ES256", "ES384", "ES512"}
+	case ed25519.PublicKey:
+		methods = []string{"EdDSA"}
+	default:
+		return nil, ErrInvalidPublicKey
+	}
+
+	return &JWTVerifier{
+		key:      key,
+		parser:   &jwt.Parser{ValidMethods: methods},
+		issuer:   issuer,
+		audience: audience,
+	}, nil
+}
+
+// Authenticate returns the principal of the token, its name is the subject
+func (v *JWTVerifier) Authenticate(token string) (Principal, error) {
+
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return v.key, nil
+	})
+	if err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if len(v.issuer) > 0 && !claims.VerifyIssuer(v.issuer, true) {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if len(v.audience) > 0 && !claims.VerifyAudience(v.audience, true) {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	sub, _ := claims["sub"].(string)
+
+	return Principal{Name: sub, Method: MethodJWT}, nil
+}
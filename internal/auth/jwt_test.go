@@ -0,0 +1,88 @@
+// +build unit
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var jwtSecret = []byte("11111111111111111111111111111111")
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	return token
+}
+
+func TestHMACJWTVerifier(t *testing.T) {
+
+	v := NewHMACJWTVerifier(jwtSecret, "https://auth.example.com", "sharesecret")
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"Valid", sign(t, jwt.SigningMethodHS256, jwtSecret, jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "aud": "sharesecret", "exp": exp}), true},
+		{"AudienceList", sign(t, jwt.SigningMethodHS512, jwtSecret, jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "aud": []string{"other", "sharesecret"}, "exp": exp}), true},
+		{"Expired", sign(t, jwt.SigningMethodHS256, jwtSecret, jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "aud": "sharesecret", "exp": time.Now().Add(-time.Minute).Unix()}), false},
+		{"WithoutExpiration", sign(t, jwt.SigningMethodHS256, jwtSecret, jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "aud": "sharesecret"}), false},
+		{"WrongIssuer", sign(t, jwt.SigningMethodHS256, jwtSecret, jwt.MapClaims{"sub": "alice", "iss": "https://other.example.com", "aud": "sharesecret", "exp": exp}), false},
+		{"WrongAudience", sign(t, jwt.SigningMethodHS256, jwtSecret, jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "aud": "other", "exp": exp}), false},
+		{"WrongSecret", sign(t, jwt.SigningMethodHS256, []byte("22222222222222222222222222222222"), jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "aud": "sharesecret", "exp": exp}), false},
+		{"AlgorithmNone", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.MapClaims{"sub": "alice", "iss": "https://auth.example.com", "aud": "sharesecret", "exp": exp}), false},
+		{"NotAToken", "ssk_notatoken", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.Authenticate(tt.token)
+
+			if tt.valid {
+				assert.Nil(t, err)
+				assert.Equal(t, Principal{Name: "alice", Method: MethodJWT}, p)
+			} else {
+				assert.Equal(t, ErrInvalidCredentials, err)
+			}
+		})
+	}
+}
+
+func TestPublicKeyJWTVerifier(t *testing.T) {
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+
+	v, err := NewPublicKeyJWTVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), "", "")
+	assert.Nil(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	p, err1 := v.Authenticate(sign(t, jwt.SigningMethodES256, key, jwt.MapClaims{"sub": "alice", "exp": exp}))
+
+	assert.Nil(t, err1)
+	assert.Equal(t, "alice", p.Name)
+
+	// a HMAC token signed with the public key must not be accepted
+	_, err2 := v.Authenticate(sign(t, jwt.SigningMethodHS256, der, jwt.MapClaims{"sub": "alice", "exp": exp}))
+
+	assert.Equal(t, ErrInvalidCredentials, err2)
+
+	_, err3 := NewPublicKeyJWTVerifier([]byte("not a key"), "", "")
+
+	assert.Equal(t, ErrInvalidPublicKey, err3)
+}
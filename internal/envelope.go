@@ -0,0 +1,74 @@
+package sharesecret
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/bernardosecades/sharesecret/internal/util"
+)
+
+// Stored content format. Legacy content is bare hex (nonce || ciphertext) so it never starts with envelopePrefix,
+// an envelope is envelopePrefix + hex(version || ...) where the rest depends on the version:
+//
+//	v2: len(keyID) || keyID || salt || nonce || ciphertext, the inner layer (nonce || ciphertext with a key derived
+//	    from the password) sealed with the server key keyID. The server key can be rotated without the password.
+//	v3: as v2 but the secret has no custom password, the key of the inner layer is expanded from the default
+//	    password with HKDF instead of derived with Argon2id.
+const (
+	envelopePrefix      = "$"
+	envelopeV2     byte = 2
+	envelopeV3     byte = 3
+)
+
+type envelope struct {
+	version byte
+	keyID   string
+	salt    []byte
+	payload []byte
+}
+
+func isEnvelope(content string) bool {
+	return strings.HasPrefix(content, envelopePrefix)
+}
+
+func parseEnvelope(content string) (envelope, error) {
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(content, envelopePrefix))
+	if err != nil || len(raw) == 0 {
+		return envelope{}, ErrUnknownFormat
+	}
+
+	e := envelope{version: raw[0]}
+	raw = raw[1:]
+
+	switch e.version {
+	case envelopeV2, envelopeV3:
+		if len(raw) < 1 || len(raw) < 1+int(raw[0]) {
+			return envelope{}, ErrUnknownFormat
+		}
+		e.keyID = string(raw[1 : 1+int(raw[0])])
+		raw = raw[1+int(raw[0]):]
+	default:
+		return envelope{}, ErrUnknownFormat
+	}
+
+	if len(raw) < util.SaltSize {
+		return envelope{}, ErrUnknownFormat
+	}
+	e.salt, e.payload = raw[:util.SaltSize], raw[util.SaltSize:]
+
+	return e, nil
+}
+
+// header returns the envelope without payload, authenticated as additional data when the payload is sealed
+func (e envelope) header() []byte {
+
+	h := []byte{e.version, byte(len(e.keyID))}
+	h = append(h, e.keyID...)
+
+	return append(h, e.salt...)
+}
+
+func (e envelope) String() string {
+	return envelopePrefix + hex.EncodeToString(append(e.header(), e.payload...))
+}
@@ -0,0 +1,108 @@
+package sharesecret
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultKeyID is the identifier of the server key when no one is configured
+const DefaultKeyID = "default"
+
+// All errors reported by the keyring
+var (
+	ErrInvalidKey   = errors.New("key secret should have 32 bytes")
+	ErrInvalidKeyID = errors.New("key identifier should have between 1 and 255 bytes without ':' or ','")
+	ErrUnknownKey   = errors.New("unknown key identifier")
+)
+
+// Keyring holds the server keys by identifier. New secrets are sealed with the active key,
+// retired keys are only used to open secrets sealed before a key rotation.
+type Keyring struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+// NewKeyring returns a keyring where activeKey (identified by activeID) seals new secrets
+// and retired (identifier => key) can still open the secrets sealed with them
+func NewKeyring(activeID string, activeKey string, retired map[string]string) (Keyring, error) {
+
+	k := Keyring{activeID: activeID, keys: make(map[string][]byte, len(retired)+1)}
+
+	for id, key := range retired {
+		if err := k.add(id, key); err != nil {
+			return Keyring{}, err
+		}
+	}
+
+	if err := k.add(activeID, activeKey); err != nil {
+		return Keyring{}, err
+	}
+
+	return k, nil
+}
+
+// ParseKeys parses keys with format "id1:key1,id2:key2"
+func ParseKeys(keys string) (map[string]string, error) {
+
+	r := make(map[string]string)
+	if len(strings.TrimSpace(keys)) == 0 {
+		return r, nil
+	}
+
+	for _, pair := range strings.Split(keys, ",") {
+		idKey := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(idKey) != 2 {
+			return nil, fmt.Errorf("key %q should have format id:key", pair)
+		}
+		r[idKey[0]] = idKey[1]
+	}
+
+	return r, nil
+}
+
+// Active returns the identifier and the key to seal new secrets
+func (k Keyring) Active() (string, []byte) {
+	return k.activeID, k.keys[k.activeID]
+}
+
+// Key returns the key with identifier id
+func (k Keyring) Key(id string) ([]byte, bool) {
+	key, ok := k.keys[id]
+	return key, ok
+}
+
+// Keys returns all keys, the active one first and then the retired ones sorted by identifier
+func (k Keyring) Keys() [][]byte {
+
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		if id != k.activeID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	keys := [][]byte{k.keys[k.activeID]}
+	for _, id := range ids {
+		keys = append(keys, k.keys[id])
+	}
+
+	return keys
+}
+
+func (k Keyring) add(id string, key string) error {
+
+	if len(id) == 0 || len(id) > 255 || strings.ContainsAny(id, ":,") {
+		return ErrInvalidKeyID
+	}
+
+	if len(key) != 32 {
+		return ErrInvalidKey
+	}
+
+	k.keys[id] = []byte(key)
+
+	return nil
+}
@@ -0,0 +1,67 @@
+// +build unit
+
+package sharesecret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKeyringActiveKeyFirst(t *testing.T) {
+
+	k, err := NewKeyring("c", "33333333333333333333333333333333", map[string]string{
+		"b": "22222222222222222222222222222222",
+		"a": "11111111111111111111111111111111",
+	})
+
+	assert.Nil(t, err)
+
+	id, key := k.Active()
+
+	assert.Equal(t, "c", id)
+	assert.Equal(t, []byte("33333333333333333333333333333333"), key)
+	assert.Equal(t, [][]byte{
+		[]byte("33333333333333333333333333333333"),
+		[]byte("11111111111111111111111111111111"),
+		[]byte("22222222222222222222222222222222"),
+	}, k.Keys())
+
+	key, ok := k.Key("b")
+
+	assert.True(t, ok)
+	assert.Equal(t, []byte("22222222222222222222222222222222"), key)
+}
+
+func TestNewKeyringInvalidKeys(t *testing.T) {
+
+	_, err1 := NewKeyring("a", "111111", nil)
+	_, err2 := NewKeyring("a", "11111111111111111111111111111111", map[string]string{"b": "2222"})
+	_, err3 := NewKeyring("", "11111111111111111111111111111111", nil)
+	_, err4 := NewKeyring("a:b", "11111111111111111111111111111111", nil)
+
+	assert.Equal(t, ErrInvalidKey, err1)
+	assert.Equal(t, ErrInvalidKey, err2)
+	assert.Equal(t, ErrInvalidKeyID, err3)
+	assert.Equal(t, ErrInvalidKeyID, err4)
+}
+
+func TestParseKeys(t *testing.T) {
+
+	keys, err := ParseKeys("a:11111111111111111111111111111111, b:22222222222222222222222222222222")
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"a": "11111111111111111111111111111111",
+		"b": "22222222222222222222222222222222",
+	}, keys)
+
+	keys, err = ParseKeys("")
+
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	_, err = ParseKeys("11111111111111111111111111111111")
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,134 @@
+// Package metrics exposes the Prometheus metrics of the secrets: the lifecycle events of the secret service
+// and how many secrets are stored.
+package metrics
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/audit"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace is the prefix of the names of the metrics
+const Namespace = "sharesecret"
+
+type sink struct {
+	created          prometheus.Counter
+	viewed           prometheus.Counter
+	deleted          prometheus.Counter
+	expired          prometheus.Counter
+	passwordFailures *prometheus.CounterVec
+}
+
+// NewSink returns an audit sink counting the events of the secret service in metrics registered in reg:
+// the secrets created, viewed, deleted and removed expired by the purge of the server, and the password failures
+// by reason (wrong_password or too_many_attempts when the secret is removed)
+func NewSink(reg prometheus.Registerer) audit.Sink {
+
+	s := &sink{
+		created: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace, Name: "secrets_created_total", Help: "Secrets created.",
+		}),
+		viewed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace, Name: "secrets_viewed_total", Help: "Views of secrets consumed.",
+		}),
+		deleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace, Name: "secrets_deleted_total", Help: "Secrets deleted with their deletion token.",
+		}),
+		expired: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace, Name: "secrets_expired_total", Help: "Expired secrets removed by the purge of the server.",
+		}),
+		passwordFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace, Name: "password_failures_total", Help: "Wrong passwords to see a secret by reason.",
+		}, []string{"reason"}),
+	}
+	reg.MustRegister(s.created, s.viewed, s.deleted, s.expired, s.passwordFailures)
+
+	return s
+}
+
+func (s *sink) Record(_ context.Context, e audit.Event) error {
+
+	switch e.Type {
+	case audit.EventCreated:
+		s.created.Inc()
+	case audit.EventViewed:
+		s.viewed.Inc()
+	case audit.EventDeleted:
+		s.deleted.Inc()
+	case audit.EventExpired:
+		// counted by secret, EventPurged has the same secrets
+		s.expired.Inc()
+	case audit.EventWrongPassword:
+		s.passwordFailures.WithLabelValues("wrong_password").Inc()
+	case audit.EventLockedOut:
+		s.passwordFailures.WithLabelValues("too_many_attempts").Inc()
+	}
+
+	return nil
+}
+
+type secretsCollector struct {
+	repository sharesecret.SecretRepository
+	timeout    time.Duration
+	desc       *prometheus.Desc
+}
+
+// NewSecretsCollector returns a collector of the gauge of the secrets not expired of r, counted on every scrape
+// waiting up to timeout for the repository. The gauge is missing when the repository fails.
+func NewSecretsCollector(r sharesecret.SecretRepository, timeout time.Duration) prometheus.Collector {
+	return &secretsCollector{
+		repository: r,
+		timeout:    timeout,
+		desc:       prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "secrets"), "Secrets not expired.", nil, nil),
+	}
+}
+
+func (c *secretsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *secretsCollector) Collect(ch chan<- prometheus.Metric) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	n, err := c.repository.CountSecrets(ctx)
+	if err != nil {
+		log.Printf("Error to count the secrets: %v\n", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
+
+// NewPurgeRegistry returns the metrics of a purge that removed deleted expired secrets at t, to be pushed to a
+// pushgateway or written to a textfile of the node exporter because the purge is not running when it is scraped
+func NewPurgeRegistry(deleted int64, t time.Time) *prometheus.Registry {
+
+	deletedGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace, Name: "purge_deleted_secrets", Help: "Expired secrets removed by the last purge.",
+	})
+	deletedGauge.Set(float64(deleted))
+
+	lastSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace, Name: "purge_last_success_timestamp_seconds", Help: "Time of the last purge.",
+	})
+	lastSuccess.Set(float64(t.Unix()))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(deletedGauge, lastSuccess)
+
+	return reg
+}
+
+// Handler returns the handler of /metrics exposing the metrics of g
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
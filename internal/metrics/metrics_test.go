@@ -0,0 +1,104 @@
+// +build unit
+
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/audit"
+	"github.com/bernardosecades/sharesecret/internal/storage/memory"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSinkCountsEvents(t *testing.T) {
+
+	reg := prometheus.NewRegistry()
+	s := NewSink(reg)
+
+	for _, e := range []audit.EventType{audit.EventCreated, audit.EventCreated, audit.EventViewed, audit.EventWrongPassword, audit.EventWrongPassword, audit.EventLockedOut, audit.EventDeleted, audit.EventExpired, audit.EventExpired} {
+		assert.Nil(t, s.Record(context.Background(), audit.NewEvent(e, "727d7040-aac7-4dc3-ab44-938bfba92ebd")))
+	}
+
+	err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP sharesecret_password_failures_total Wrong passwords to see a secret by reason.
+# TYPE sharesecret_password_failures_total counter
+sharesecret_password_failures_total{reason="too_many_attempts"} 1
+sharesecret_password_failures_total{reason="wrong_password"} 2
+# HELP sharesecret_secrets_created_total Secrets created.
+# TYPE sharesecret_secrets_created_total counter
+sharesecret_secrets_created_total 2
+# HELP sharesecret_secrets_deleted_total Secrets deleted with their deletion token.
+# TYPE sharesecret_secrets_deleted_total counter
+sharesecret_secrets_deleted_total 1
+# HELP sharesecret_secrets_expired_total Expired secrets removed by the purge of the server.
+# TYPE sharesecret_secrets_expired_total counter
+sharesecret_secrets_expired_total 2
+# HELP sharesecret_secrets_viewed_total Views of secrets consumed.
+# TYPE sharesecret_secrets_viewed_total counter
+sharesecret_secrets_viewed_total 1
+`))
+
+	assert.Nil(t, err)
+}
+
+func TestSinkCountsExpiredSecretsOnce(t *testing.T) {
+
+	reg := prometheus.NewRegistry()
+	r := memory.NewMemorySecretRepository()
+	ctx := context.Background()
+	_, _ = r.CreateSecret(ctx, "this is my secret expired", false, time.Now().UTC().Add(-time.Hour), 1, "")
+	_, _ = r.CreateSecret(ctx, "this is my other secret expired", false, time.Now().UTC().Add(-time.Hour), 1, "")
+	_, _ = r.CreateSecret(ctx, "this is my secret", false, time.Now().UTC().Add(time.Hour), 1, "")
+
+	// the purge records every expired secret and then the purge with how many
+	n, err := sharesecret.PurgeSecretsExpired(ctx, r, NewSink(reg))
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP sharesecret_secrets_expired_total Expired secrets removed by the purge of the server.
+# TYPE sharesecret_secrets_expired_total counter
+sharesecret_secrets_expired_total 2
+`), "sharesecret_secrets_expired_total")
+
+	assert.Nil(t, err)
+}
+
+func TestSecretsCollectorCountsSecretsNotExpired(t *testing.T) {
+
+	r := memory.NewMemorySecretRepository()
+	ctx := context.Background()
+	_, _ = r.CreateSecret(ctx, "this is my secret", false, time.Now().UTC().Add(time.Hour), 1, "")
+	_, _ = r.CreateSecret(ctx, "this is my other secret", false, time.Now().UTC().Add(time.Hour), 1, "")
+	_, _ = r.CreateSecret(ctx, "this is my secret expired", false, time.Now().UTC().Add(-time.Hour), 1, "")
+
+	err := testutil.CollectAndCompare(NewSecretsCollector(r, time.Second), strings.NewReader(`
+# HELP sharesecret_secrets Secrets not expired.
+# TYPE sharesecret_secrets gauge
+sharesecret_secrets 2
+`))
+
+	assert.Nil(t, err)
+}
+
+func TestPurgeRegistry(t *testing.T) {
+
+	err := testutil.GatherAndCompare(NewPurgeRegistry(3, time.Unix(1615802400, 0)), strings.NewReader(`
+# HELP sharesecret_purge_deleted_secrets Expired secrets removed by the last purge.
+# TYPE sharesecret_purge_deleted_secrets gauge
+sharesecret_purge_deleted_secrets 3
+# HELP sharesecret_purge_last_success_timestamp_seconds Time of the last purge.
+# TYPE sharesecret_purge_last_success_timestamp_seconds gauge
+sharesecret_purge_last_success_timestamp_seconds 1.6158024e+09
+`))
+
+	assert.Nil(t, err)
+}
@@ -0,0 +1,77 @@
+package sharesecret
+
+import (
+	"time"
+
+	"github.com/bernardosecades/sharesecret/internal/audit"
+)
+
+// Option configures the secret service
+type Option func(*secretService)
+
+// DefaultMaxPasswordAttempts is the number of wrong passwords before a secret is deleted
+const DefaultMaxPasswordAttempts = 5
+
+// DefaultMaxConcurrentDerivations is the number of keys derived from custom passwords at once, every derivation
+// uses util.ArgonMemory (64 MiB)
+const DefaultMaxConcurrentDerivations = 8
+
+// TTLPolicy limits the time to live that clients can request for a secret
+type TTLPolicy struct {
+	Min     time.Duration
+	Max     time.Duration
+	Default time.Duration
+}
+
+// DefaultTTLPolicy keeps secrets for 5 days when clients do not choose, from 5 minutes up to 30 days
+var DefaultTTLPolicy = TTLPolicy{Min: 5 * time.Minute, Max: 30 * 24 * time.Hour, Default: 5 * 24 * time.Hour}
+
+// NewTTLPolicy returns a policy where min <= def <= max
+func NewTTLPolicy(min time.Duration, max time.Duration, def time.Duration) (TTLPolicy, error) {
+
+	if min <= 0 || min > def || def > max {
+		return TTLPolicy{}, ErrInvalidTTLPolicy
+	}
+
+	return TTLPolicy{Min: min, Max: max, Default: def}, nil
+}
+
+// WithTTLPolicy sets the policy of time to live of the secrets, DefaultTTLPolicy if it is not set
+func WithTTLPolicy(p TTLPolicy) Option {
+	return func(s *secretService) {
+		s.ttlPolicy = p
+	}
+}
+
+// WithMaxPasswordAttempts sets the number of wrong passwords before a secret is deleted, zero means unlimited
+func WithMaxPasswordAttempts(n int) Option {
+	return func(s *secretService) {
+		s.maxPasswordAttempts = n
+	}
+}
+
+// WithMaxConcurrentDerivations limits the keys derived from custom passwords at once, the rest of the calls wait
+// for their turn so the memory used by Argon2id is bounded. DefaultMaxConcurrentDerivations if it is not set.
+func WithMaxConcurrentDerivations(n int) Option {
+	return func(s *secretService) {
+		if n > 0 {
+			s.derivations = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithStorageTimeout limits the time of every call to the repository, zero means no limit
+func WithStorageTimeout(d time.Duration) Option {
+	return func(s *secretService) {
+		if d > 0 {
+			s.repository = timeoutRepository{repository: s.repository, timeout: d}
+		}
+	}
+}
+
+// WithAuditSink records the lifecycle events of the secrets in sink, audit.Discard if it is not set
+func WithAuditSink(sink audit.Sink) Option {
+	return func(s *secretService) {
+		s.auditSink = sink
+	}
+}
@@ -0,0 +1,55 @@
+package sharesecret
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bernardosecades/sharesecret/internal/audit"
+)
+
+// PurgeSecretsExpired removes the expired secrets of r and returns how many, sink records each of them and the
+// purge with how many
+func PurgeSecretsExpired(ctx context.Context, r SecretRepository, sink audit.Sink) (int64, error) {
+
+	ids, err := r.RemoveSecretsExpired(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := sink.Record(ctx, audit.NewEvent(audit.EventExpired, id)); err != nil {
+			log.Printf("Error to record the expired secret %s: %v\n", id, err)
+		}
+	}
+
+	n := int64(len(ids))
+	if n > 0 {
+		e := audit.NewEvent(audit.EventPurged, "")
+		e.Count = n
+		if err := sink.Record(ctx, e); err != nil {
+			log.Printf("Error to record the purge: %v\n", err)
+		}
+	}
+
+	return n, nil
+}
+
+// RunPurge purges the expired secrets of r every interval until ctx is done, for the storages that can not be
+// purged by another process (e.g. the memory of the server). A failed purge is logged and retried on the next one.
+func RunPurge(ctx context.Context, r SecretRepository, sink audit.Sink, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := PurgeSecretsExpired(ctx, r, sink); err != nil && ctx.Err() == nil {
+				log.Printf("Error to remove the expired secrets: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// +build unit
+
+package sharesecret
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bernardosecades/sharesecret/internal/audit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPurgeSecretsExpiredIsAudited(t *testing.T) {
+
+	repo := new(MockRepository)
+	repo.On("RemoveSecretsExpired").Return([]string{"id1", "id2", "id3"}, nil)
+
+	sink := &recordingSink{}
+	n, err := PurgeSecretsExpired(context.Background(), repo, sink)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, []audit.EventType{audit.EventExpired, audit.EventExpired, audit.EventExpired, audit.EventPurged}, sink.types())
+	assert.Equal(t, "id1", sink.events[0].SecretID)
+	assert.Equal(t, "id3", sink.events[2].SecretID)
+	assert.Equal(t, int64(3), sink.events[3].Count)
+}
+
+func TestRunPurgeUntilContextIsDone(t *testing.T) {
+
+	purged := make(chan struct{}, 10)
+	repo := new(MockRepository)
+	repo.
+		On("RemoveSecretsExpired").
+		Run(func(args mock.Arguments) { purged <- struct{}{} }).
+		Return(nil, errors.New("storage failure"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunPurge(ctx, repo, audit.Discard, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// a failed purge does not stop the next ones
+	<-purged
+	<-purged
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPurge did not stop when the context was canceled")
+	}
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type memoryStore struct {
+	idle time.Duration
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns a store keeping the buckets in memory, a bucket not used for idle is removed
+// (it has to be longer than the period of the limits so a full bucket is removed)
+func NewMemoryStore(idle time.Duration) Store {
+	return &memoryStore{idle: idle, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+func (s *memoryStore) Take(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(limit.Rate, limit.Burst)}
+		s.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay, nil
+	}
+
+	return true, 0, nil
+}
+
+// sweep removes the idle buckets, at most once per idle period
+func (s *memoryStore) sweep(now time.Time) {
+
+	if now.Sub(s.lastSweep) < s.idle {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.lastSeen) >= s.idle {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package ratelimit limits the requests by key (e.g. peer IP or secret ID) with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// All errors reported by the rate limits
+var (
+	ErrInvalidLimit  = errors.New(`limit should have format "<requests>/<period>" (e.g. "60/1m") or be "off"`)
+	ErrLimitExceeded = errors.New("too many requests, retry later")
+)
+
+// Limit is a token bucket of Burst tokens refilled at Rate tokens per second, it does not limit if Burst is 0
+type Limit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+// Enabled returns true if the limit limits the requests
+func (l Limit) Enabled() bool {
+	return l.Burst > 0
+}
+
+// ParseLimit parses a limit with format "<requests>/<period>" (e.g. "60/1m"), a burst of requests is allowed
+// and refilled over period. "off" (or an empty string) returns a disabled limit.
+func ParseLimit(s string) (Limit, error) {
+
+	if len(s) == 0 || s == "off" {
+		return Limit{}, nil
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	return Limit{Rate: rate.Every(period / time.Duration(requests)), Burst: requests}, nil
+}
+
+// Period returns how long an empty bucket takes to be full
+func (l Limit) Period() time.Duration {
+
+	if !l.Enabled() || l.Rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(l.Burst) / float64(l.Rate) * float64(time.Second))
+}
+
+func (l Limit) String() string {
+
+	if !l.Enabled() {
+		return "off"
+	}
+
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period())
+}
+
+// Store keeps the token buckets, it can be shared by several servers (e.g. in Redis)
+type Store interface {
+	// Take takes a token from the bucket of key, it returns false and how long to wait for the next token
+	// if the bucket is empty
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
@@ -0,0 +1,98 @@
+// +build unit
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+
+	l1, err1 := ParseLimit("60/1m")
+
+	assert.Nil(t, err1)
+	assert.True(t, l1.Enabled())
+	assert.Equal(t, 60, l1.Burst)
+	assert.Equal(t, time.Minute, l1.Period())
+	assert.Equal(t, "60/1m0s", l1.String())
+
+	for _, s := range []string{"", "off"} {
+		l, err := ParseLimit(s)
+
+		assert.Nil(t, err)
+		assert.False(t, l.Enabled())
+		assert.Equal(t, "off", l.String())
+	}
+
+	for _, s := range []string{"60", "0/1m", "-1/1m", "a/1m", "60/0s", "60/minute"} {
+		_, err := ParseLimit(s)
+
+		assert.Equal(t, ErrInvalidLimit, err, s)
+	}
+}
+
+func TestMemoryStoreTakesUntilEmptyAndRefills(t *testing.T) {
+
+	now := time.Now()
+	s := NewMemoryStore(time.Hour).(*memoryStore)
+	s.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	limit, _ := ParseLimit("2/1m")
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := s.Take(ctx, "ip:10.0.0.1", limit)
+
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+
+	ok1, retryAfter1, err1 := s.Take(ctx, "ip:10.0.0.1", limit)
+
+	assert.Nil(t, err1)
+	assert.False(t, ok1)
+	assert.Equal(t, 30*time.Second, retryAfter1)
+
+	// other keys have their own bucket
+	ok2, _, _ := s.Take(ctx, "ip:10.0.0.2", limit)
+
+	assert.True(t, ok2)
+
+	// the rejected calls do not take tokens
+	now = now.Add(30 * time.Second)
+	ok3, _, _ := s.Take(ctx, "ip:10.0.0.1", limit)
+
+	assert.True(t, ok3)
+
+	ok4, retryAfter4, _ := s.Take(ctx, "ip:10.0.0.1", limit)
+
+	assert.False(t, ok4)
+	assert.Equal(t, 30*time.Second, retryAfter4)
+}
+
+func TestMemoryStoreRemovesIdleBuckets(t *testing.T) {
+
+	now := time.Now()
+	s := NewMemoryStore(time.Minute).(*memoryStore)
+	s.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	limit, _ := ParseLimit("1/1m")
+
+	_, _, _ = s.Take(ctx, "ip:10.0.0.1", limit)
+	now = now.Add(30 * time.Second)
+	_, _, _ = s.Take(ctx, "ip:10.0.0.2", limit)
+
+	assert.Len(t, s.buckets, 2)
+
+	now = now.Add(45 * time.Second)
+	ok, _, _ := s.Take(ctx, "ip:10.0.0.3", limit)
+
+	assert.True(t, ok)
+	assert.Len(t, s.buckets, 2)
+	assert.NotContains(t, s.buckets, "ip:10.0.0.1")
+}
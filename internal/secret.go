@@ -3,9 +3,14 @@ package sharesecret
 import "time"
 
 type Secret struct {
-	ID        string
-	Content   string
-	CustomPwd bool
-	CreatedAt time.Time
-	ExpiredAt time.Time
+	ID                string
+	Content           string
+	CustomPwd         bool
+	CreatedAt         time.Time
+	ExpiredAt         time.Time
+	RemainingViews    int
+	FailedAttempts    int
+	DeletionTokenHash string
+	// DeletionToken is only known when the secret is created, we store its hash
+	DeletionToken string
 }
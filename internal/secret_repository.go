@@ -1,11 +1,25 @@
 package sharesecret
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
+// SecretRepository stores the secrets, a secret that does not exist or is expired is reported with ErrSecretNotFound
 type SecretRepository interface {
-	GetSecret(id string) (Secret, error)
-	CreateSecret(content string, customPwd bool, expire time.Time) (Secret, error)
-	RemoveSecret(id string) error
-	RemoveSecretsExpired() (int64, error)
-	HasSecretWithCustomPwd(id string) (bool, error)
+	GetSecret(ctx context.Context, id string) (Secret, error)
+	CreateSecret(ctx context.Context, content string, customPwd bool, expire time.Time, maxViews int, deletionTokenHash string) (Secret, error)
+	// ClaimSecret atomically consumes a view of the secret and returns it with the remaining views,
+	// the secret is removed when there are no more views so concurrent claims of its last view can not both succeed
+	ClaimSecret(ctx context.Context, id string) (Secret, error)
+	// IncrementFailedAttempts registers a wrong password to see the secret and returns the failed attempts
+	IncrementFailedAttempts(ctx context.Context, id string) (int, error)
+	RemoveSecret(ctx context.Context, id string) error
+	// RemoveSecretsExpired removes the expired secrets and returns their IDs
+	RemoveSecretsExpired(ctx context.Context) ([]string, error)
+	HasSecretWithCustomPwd(ctx context.Context, id string) (bool, error)
+	GetSecrets(ctx context.Context) ([]Secret, error)
+	// CountSecrets returns how many secrets are not expired
+	CountSecrets(ctx context.Context) (int64, error)
+	UpdateSecretContent(ctx context.Context, id string, content string) error
 }
@@ -1,10 +1,16 @@
 package sharesecret
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
-	"github.com/bernardosecades/sharesecret/internal/util"
+	"fmt"
+	"log"
 	"time"
+
+	"github.com/bernardosecades/sharesecret/internal/audit"
+	"github.com/bernardosecades/sharesecret/internal/util"
 )
 
 // All errors reported by the service
@@ -17,63 +23,141 @@ var (
 	ErrPassTooLong    = errors.New("password too long")
 	ErrPassToDecrypt  = errors.New("error password to decrypt")
 	ErrToEncrypt      = errors.New("error to encrypt")
+	ErrToDecrypt      = errors.New("error to decrypt")
+	ErrUnknownFormat  = errors.New("unknown format of the secret content")
+	ErrTTLTooShort    = errors.New("time to live too short")
+	ErrTTLTooLong     = errors.New("time to live too long")
+
+	ErrInvalidMaxViews  = errors.New("max views should be between 1 and 100")
+	ErrTooManyAttempts  = errors.New("too many wrong passwords, the secret was deleted")
+	ErrInvalidToken     = errors.New("invalid deletion token")
+	ErrInvalidTTLPolicy = errors.New("time to live policy should have 0 < min <= default <= max")
+
+	errNeedsCustomPwd = errors.New("the secret needs its custom password to be rekeyed")
 )
 
+// RekeyResult is what RekeySecrets did with the secrets
+type RekeyResult struct {
+	// Rekeyed secrets are sealed now with the active key
+	Rekeyed int64
+	// Skipped secrets have a custom password and were stored before envelope v2, they can not be rekeyed and may
+	// need a retired key until they expire
+	Skipped int64
+}
+
 type SecretService interface {
-	GetContentSecret(id string, password string) (string, error)
-	CreateSecret(rawContent string, password string) (Secret, error)
+	// GetContentSecret returns the secret with its content decrypted and consumes one of its views
+	GetContentSecret(ctx context.Context, id string, password string) (Secret, error)
+	// GetSecretInfo returns the secret without content, it does not consume any view
+	GetSecretInfo(ctx context.Context, id string) (Secret, error)
+	// CreateSecret stores the secret during ttl (zero means the default of the TTLPolicy)
+	// to be seen maxViews times (zero means 1)
+	CreateSecret(ctx context.Context, rawContent string, password string, ttl time.Duration, maxViews int) (Secret, error)
+	// DeleteSecret deletes the secret before it is seen, token is the DeletionToken returned on creation
+	DeleteSecret(ctx context.Context, id string, token string) error
+	// RekeySecrets seals again with the active key the secrets sealed with retired keys and returns how many
+	// were updated, and how many were skipped because they have a custom password and were stored before envelope v2
+	RekeySecrets(ctx context.Context) (RekeyResult, error)
 }
 
 type secretService struct {
 	repository SecretRepository
-	key        string
+	keyring    Keyring
 	defaultPwd string
+	ttlPolicy  TTLPolicy
+	auditSink  audit.Sink
+
+	// derivations has a slot per key being derived with Argon2id
+	derivations chan struct{}
+
+	maxPasswordAttempts int
 }
 
-func NewSecretService(r SecretRepository, key string, defaultPwd string) SecretService {
+func NewSecretService(r SecretRepository, key string, defaultPwd string, opts ...Option) SecretService {
 
-	if len(key) != 32 {
-		panic("key secret should have 32 bytes")
+	keyring, err := NewKeyring(DefaultKeyID, key, nil)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return NewSecretServiceWithKeyring(r, keyring, defaultPwd, opts...)
+}
+
+func NewSecretServiceWithKeyring(r SecretRepository, keyring Keyring, defaultPwd string, opts ...Option) SecretService {
+
+	s := &secretService{repository: r, keyring: keyring, defaultPwd: defaultPwd, ttlPolicy: DefaultTTLPolicy, auditSink: audit.Discard, maxPasswordAttempts: DefaultMaxPasswordAttempts}
+	s.derivations = make(chan struct{}, DefaultMaxConcurrentDerivations)
+	for _, opt := range opts {
+		opt(s)
 	}
 
-	return &secretService{r, key, defaultPwd}
+	return s
 }
 
-func (s *secretService) GetContentSecret(id string, password string) (string, error) {
+func (s *secretService) GetContentSecret(ctx context.Context, id string, password string) (Secret, error) {
 
-	hasPass, err := s.hasSecretWithCustomPwd(id)
+	hasPass, err := s.hasSecretWithCustomPwd(ctx, id)
 
 	if err != nil {
-		return "", ErrSecretNotFound
+		return Secret{}, notFound(err)
 	}
 
 	if hasPass && len(password) == 0 {
-		return "", ErrMissingPass
+		return Secret{}, ErrMissingPass
 	}
 
 	if !hasPass && len(password) > 0 {
-		return "", ErrNoPassRequired
+		return Secret{}, ErrNoPassRequired
 	}
 
 	if len(password) == 0 {
 		password = s.defaultPwd
 	}
 
-	secret, err := s.getSecret(id)
+	secret, err := s.repository.GetSecret(ctx, id)
 	if err != nil {
-		return "", ErrSecretNotFound
+		return Secret{}, notFound(err)
 	}
 
-	content, err := s.decryptContentSecret(secret.Content, password)
+	// the secret is only consumed once we know the password can decrypt it
+	content, err := s.decryptContentSecret(ctx, secret.Content, password)
 
 	if err != nil {
-		return "", ErrPassToDecrypt
+		// a failure of the server (e.g. its key is missing from the keyring) is not a wrong password
+		if !errors.Is(err, ErrPassToDecrypt) {
+			return Secret{}, err
+		}
+		if hasPass {
+			return Secret{}, s.failedAttempt(ctx, id)
+		}
+		// the default password is the one of the server, the caller sent none
+		return Secret{}, ErrToDecrypt
 	}
 
-	return content, nil
+	secret, err = s.getSecret(ctx, id)
+	if err != nil {
+		return Secret{}, notFound(err)
+	}
+
+	secret.Content = content
+	s.record(ctx, audit.EventViewed, id)
+
+	return secret, nil
+}
+
+func (s *secretService) GetSecretInfo(ctx context.Context, id string) (Secret, error) {
+
+	secret, err := s.repository.GetSecret(ctx, id)
+	if err != nil {
+		return Secret{}, notFound(err)
+	}
+
+	secret.Content = ""
+
+	return secret, nil
 }
 
-func (s *secretService) CreateSecret(rawContent string, password string) (Secret, error) {
+func (s *secretService) CreateSecret(ctx context.Context, rawContent string, password string, ttl time.Duration, maxViews int) (Secret, error) {
 
 	if len(rawContent) == 0 {
 		return Secret{}, ErrEmptyContent
@@ -87,68 +171,324 @@ func (s *secretService) CreateSecret(rawContent string, password string) (Secret
 		return Secret{}, ErrPassTooLong
 	}
 
+	if ttl == 0 {
+		ttl = s.ttlPolicy.Default
+	}
+
+	if ttl < s.ttlPolicy.Min {
+		return Secret{}, ErrTTLTooShort
+	}
+
+	if ttl > s.ttlPolicy.Max {
+		return Secret{}, ErrTTLTooLong
+	}
+
+	if maxViews == 0 {
+		maxViews = 1
+	}
+
+	if maxViews < 0 || maxViews > 100 {
+		return Secret{}, ErrInvalidMaxViews
+	}
+
 	customPwd := true
 	if len(password) == 0 {
 		customPwd = false
 		password = s.defaultPwd
 	}
 
-	content, err := s.encryptContentSecret(rawContent, password)
+	content, err := s.encryptContentSecret(ctx, rawContent, password, customPwd)
 
 	if err != nil {
+		if aborted(err) {
+			return Secret{}, err
+		}
 		return Secret{}, ErrToEncrypt
 	}
 
-	expire := time.Now().UTC().AddDate(0, 0, 5)
-	secret, err := s.repository.CreateSecret(content, customPwd, expire)
+	token, err := util.NewToken()
+	if err != nil {
+		return Secret{}, err
+	}
+
+	expire := time.Now().UTC().Add(ttl)
+	secret, err := s.repository.CreateSecret(ctx, content, customPwd, expire, maxViews, util.HashToken(token))
 	if err != nil {
 		return Secret{}, err
 	}
 
+	secret.DeletionToken = token
+	s.record(ctx, audit.EventCreated, secret.ID)
+
 	return secret, nil
 }
 
-func (s secretService) hasSecretWithCustomPwd(id string) (bool, error) {
+func (s *secretService) DeleteSecret(ctx context.Context, id string, token string) error {
+
+	secret, err := s.repository.GetSecret(ctx, id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	// secrets created before deletion tokens existed have no hash and can not be deleted
+	if len(secret.DeletionTokenHash) == 0 || subtle.ConstantTimeCompare([]byte(secret.DeletionTokenHash), []byte(util.HashToken(token))) != 1 {
+		return ErrInvalidToken
+	}
+
+	if err := s.repository.RemoveSecret(ctx, id); err != nil {
+		return notFound(err)
+	}
+	s.record(ctx, audit.EventDeleted, id)
 
-	return s.repository.HasSecretWithCustomPwd(id)
+	return nil
 }
 
-func (s secretService) getSecret(id string) (Secret, error) {
-	secret, err := s.repository.GetSecret(id)
+func (s *secretService) RekeySecrets(ctx context.Context) (RekeyResult, error) {
+
+	var r RekeyResult
+
+	secrets, err := s.repository.GetSecrets(ctx)
 	if err != nil {
-		return Secret{}, err
+		return r, err
+	}
+
+	for _, secret := range secrets {
+		if err := ctx.Err(); err != nil {
+			return r, err
+		}
+
+		content, ok, err := s.rekeyContentSecret(ctx, secret)
+		if errors.Is(err, errNeedsCustomPwd) {
+			r.Skipped++
+			continue
+		}
+
+		if err != nil {
+			return r, fmt.Errorf("rekey secret %s: %w", secret.ID, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err := s.repository.UpdateSecretContent(ctx, secret.ID, content); err != nil {
+			return r, err
+		}
+		r.Rekeyed++
+	}
+
+	return r, nil
+}
+
+// notFound returns ErrSecretNotFound when the repository has no secret, other errors (e.g. the storage is down
+// or the request was aborted) are returned as they are so they are not reported as a missing secret
+func notFound(err error) error {
+	if errors.Is(err, ErrSecretNotFound) {
+		return ErrSecretNotFound
+	}
+
+	return err
+}
+
+// aborted returns if err is because the context was canceled or its deadline exceeded
+func aborted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (s secretService) hasSecretWithCustomPwd(ctx context.Context, id string) (bool, error) {
+
+	return s.repository.HasSecretWithCustomPwd(ctx, id)
+}
+
+// failedAttempt registers a wrong password for the secret and deletes it when it reaches the max attempts
+func (s secretService) failedAttempt(ctx context.Context, id string) error {
+	s.record(ctx, audit.EventWrongPassword, id)
+
+	attempts, err := s.repository.IncrementFailedAttempts(ctx, id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if s.maxPasswordAttempts > 0 && attempts >= s.maxPasswordAttempts {
+		if err := s.repository.RemoveSecret(ctx, id); err == nil {
+			s.record(ctx, audit.EventLockedOut, id)
+		}
+		return ErrTooManyAttempts
 	}
 
-	err = s.repository.RemoveSecret(id)
+	return ErrPassToDecrypt
+}
+
+// record records the event of the secret id, a failing sink is logged and does not fail the operation
+func (s secretService) record(ctx context.Context, t audit.EventType, id string) {
+	if err := s.auditSink.Record(ctx, audit.NewEvent(t, id)); err != nil {
+		log.Printf("Error to record the event %s of the secret %s: %v\n", t, id, err)
+	}
+}
+
+func (s secretService) getSecret(ctx context.Context, id string) (Secret, error) {
+	return s.repository.ClaimSecret(ctx, id)
+}
+
+// rekeyContentSecret returns the content of the secret sealed with the active key, false if it is already sealed
+// with it, errNeedsCustomPwd if it can not be rekeyed without the custom password
+func (s *secretService) rekeyContentSecret(ctx context.Context, secret Secret) (string, bool, error) {
+
+	if isEnvelope(secret.Content) {
+		e, err := parseEnvelope(secret.Content)
+		if err != nil {
+			return "", false, err
+		}
+
+		if activeID, _ := s.keyring.Active(); e.keyID == activeID {
+			return "", false, nil
+		}
 
+		inner, err := s.unseal(e)
+		if err != nil {
+			return "", false, err
+		}
+
+		content, err := s.seal(e.version, e.salt, inner)
+		if err != nil {
+			return "", false, err
+		}
+
+		return content, true, nil
+	}
+
+	if secret.CustomPwd {
+		return "", false, errNeedsCustomPwd
+	}
+
+	rawContent, err := s.decryptContentSecret(ctx, secret.Content, s.defaultPwd)
 	if err != nil {
-		return Secret{}, err
+		return "", false, err
 	}
 
-	return secret, nil
+	content, err := s.encryptContentSecret(ctx, rawContent, s.defaultPwd, false)
+	if err != nil {
+		return "", false, err
+	}
+
+	return content, true, nil
 }
 
-func (s *secretService) decryptContentSecret(content string, password string) (string, error) {
-	decodeContent, _ := hex.DecodeString(content)
-	key := []byte(s.key)
-	copy(key[:], password)
-	decryptContent, err := util.Decrypt(key, decodeContent)
+func (s *secretService) decryptContentSecret(ctx context.Context, content string, password string) (string, error) {
 
+	if !isEnvelope(content) {
+		return s.decryptLegacyContentSecret(content, password)
+	}
+
+	e, err := parseEnvelope(content)
 	if err != nil {
 		return "", err
 	}
 
+	var decryptContent []byte
+	switch e.version {
+	case envelopeV3:
+		var inner []byte
+		if inner, err = s.unseal(e); err != nil {
+			return "", err
+		}
+		decryptContent, err = util.Decrypt(util.ExpandKey([]byte(password), e.salt), inner)
+	default:
+		var inner, key []byte
+		if inner, err = s.unseal(e); err != nil {
+			return "", err
+		}
+		if key, err = s.deriveKey(ctx, password, e.salt); err != nil {
+			return "", err
+		}
+		decryptContent, err = util.Decrypt(key, inner)
+	}
+
+	// only the inner layer is encrypted with the password
+	if err != nil {
+		return "", ErrPassToDecrypt
+	}
+
 	return string(decryptContent), nil
 }
 
-func (s *secretService) encryptContentSecret(content string, password string) (string, error) {
-	key := []byte(s.key)
-	copy(key[:], password)
+// decryptLegacyContentSecret decrypts content stored before envelopes existed (bare hex of nonce and ciphertext)
+// where the password overwrote the first bytes of the server key
+func (s *secretService) decryptLegacyContentSecret(content string, password string) (string, error) {
+	decodeContent, _ := hex.DecodeString(content)
+
+	for _, serverKey := range s.keyring.Keys() {
+		key := make([]byte, len(serverKey))
+		copy(key, serverKey)
+		copy(key[:], password)
+
+		if decryptContent, err := util.Decrypt(key, decodeContent); err == nil {
+			return string(decryptContent), nil
+		}
+	}
+
+	// the password and the server key are mixed, a wrong server key can not be told from a wrong password
+	return "", ErrPassToDecrypt
+}
+
+// encryptContentSecret encrypts the content with a key derived from the password and a random salt, then seals
+// the result with the active server key (see envelope v2). The default password is only expanded (envelope v3).
+func (s *secretService) encryptContentSecret(ctx context.Context, content string, password string, customPwd bool) (string, error) {
+	salt, err := util.NewSalt()
+	if err != nil {
+		return "", err
+	}
+
+	version, key := envelopeV3, util.ExpandKey([]byte(password), salt)
+	if customPwd {
+		version = envelopeV2
+		if key, err = s.deriveKey(ctx, password, salt); err != nil {
+			return "", err
+		}
+	}
+
 	encryptContent, err := util.Encrypt(key, []byte(content))
 
 	if err != nil {
 		return "", err
 	}
 
-	return hex.EncodeToString(encryptContent), nil
+	return s.seal(version, salt, encryptContent)
+}
+
+// deriveKey derives the key of the password with Argon2id once there is a free slot, or returns the error of ctx
+// if it is done before
+func (s *secretService) deriveKey(ctx context.Context, password string, salt []byte) ([]byte, error) {
+	select {
+	case s.derivations <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.derivations }()
+
+	return util.DeriveKey([]byte(password), salt), nil
+}
+
+// seal encrypts the inner layer with the active server key in an envelope of version v2 or v3
+func (s *secretService) seal(version byte, salt []byte, inner []byte) (string, error) {
+	keyID, key := s.keyring.Active()
+	e := envelope{version: version, keyID: keyID, salt: salt}
+
+	payload, err := util.EncryptWithAdditionalData(key, inner, e.header())
+	if err != nil {
+		return "", err
+	}
+	e.payload = payload
+
+	return e.String(), nil
+}
+
+// unseal decrypts the inner layer with the server key used to seal it
+func (s *secretService) unseal(e envelope) ([]byte, error) {
+	key, ok := s.keyring.Key(e.keyID)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return util.DecryptWithAdditionalData(key, e.payload, e.header())
 }
@@ -3,10 +3,15 @@
 package sharesecret
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/bernardosecades/sharesecret/internal/audit"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -17,30 +22,57 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) CreateSecret(content string, customPwd bool, expire time.Time) (Secret, error) {
-	args := m.Called(content, customPwd, expire)
+func (m *MockRepository) CreateSecret(ctx context.Context, content string, customPwd bool, expire time.Time, maxViews int, deletionTokenHash string) (Secret, error) {
+	args := m.Called(content, customPwd, expire, maxViews, deletionTokenHash)
+	return args.Get(0).(Secret), args.Error(1)
+}
+
+func (m *MockRepository) IncrementFailedAttempts(ctx context.Context, id string) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) ClaimSecret(ctx context.Context, id string) (Secret, error) {
+	args := m.Called(id)
 	return args.Get(0).(Secret), args.Error(1)
 }
 
-func (m *MockRepository) RemoveSecret(id string) error {
+func (m *MockRepository) RemoveSecret(ctx context.Context, id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockRepository) RemoveSecretsExpired() (int64, error) {
-	panic("implement me")
+func (m *MockRepository) RemoveSecretsExpired(ctx context.Context) ([]string, error) {
+	args := m.Called()
+	ids, _ := args.Get(0).([]string)
+	return ids, args.Error(1)
 }
 
-func (m *MockRepository) HasSecretWithCustomPwd(id string) (bool, error) {
+func (m *MockRepository) HasSecretWithCustomPwd(ctx context.Context, id string) (bool, error) {
 	args := m.Called(id)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockRepository) GetSecret(id string) (Secret, error) {
+func (m *MockRepository) GetSecret(ctx context.Context, id string) (Secret, error) {
 	args := m.Called(id)
 	return args.Get(0).(Secret), args.Error(1)
 }
 
+func (m *MockRepository) GetSecrets(ctx context.Context) ([]Secret, error) {
+	args := m.Called()
+	return args.Get(0).([]Secret), args.Error(1)
+}
+
+func (m *MockRepository) CountSecrets(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) UpdateSecretContent(ctx context.Context, id string, content string) error {
+	args := m.Called(id, content)
+	return args.Error(0)
+}
+
 func TestGetContentSecretNoPassRequiredToSeeSecret(t *testing.T) {
 
 	key := "11111111111111111111111111111111"
@@ -61,14 +93,20 @@ func TestGetContentSecretNoPassRequiredToSeeSecret(t *testing.T) {
 			ExpiredAt: time.Now(),
 		}, nil)
 	mockRepo.
-		On("RemoveSecret", id).
-		Return(nil)
+		On("ClaimSecret", id).
+		Return(Secret{
+			ID:        id,
+			Content:   "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+			CustomPwd: false,
+			CreatedAt: time.Now(),
+			ExpiredAt: time.Now(),
+		}, nil)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	cs, err := sut.GetContentSecret(id, "")
+	cs, err := sut.GetContentSecret(context.Background(), id, "")
 
 	assert.Nil(t, err)
-	assert.Equal(t, "My name is Bernie", cs)
+	assert.Equal(t, "My name is Bernie", cs.Content)
 }
 
 func TestGetContentSecretWithPassRequiredToSeeSecret(t *testing.T) {
@@ -91,14 +129,20 @@ func TestGetContentSecretWithPassRequiredToSeeSecret(t *testing.T) {
 			ExpiredAt: time.Now(),
 		}, nil)
 	mockRepo.
-		On("RemoveSecret", id).
-		Return(nil)
+		On("ClaimSecret", id).
+		Return(Secret{
+			ID:        id,
+			Content:   "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+			CustomPwd: false,
+			CreatedAt: time.Now(),
+			ExpiredAt: time.Now(),
+		}, nil)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	cs, err := sut.GetContentSecret(id, pass)
+	cs, err := sut.GetContentSecret(context.Background(), id, pass)
 
 	assert.Nil(t, err)
-	assert.Equal(t, "My name is Bernie", cs)
+	assert.Equal(t, "My name is Bernie", cs.Content)
 }
 
 func TestGetContentSecretWithPassButIsNotRequiredToSeeSecret(t *testing.T) {
@@ -113,7 +157,7 @@ func TestGetContentSecretWithPassButIsNotRequiredToSeeSecret(t *testing.T) {
 		Return(false, nil)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	cs, err := sut.GetContentSecret(id, pass)
+	cs, err := sut.GetContentSecret(context.Background(), id, pass)
 
 	assert.NotNil(t, err)
 	assert.Equal(t, "the password is not required", err.Error())
@@ -132,7 +176,7 @@ func TestGetContentSecretButNotExistOrWasViewed(t *testing.T) {
 		Return(false, ErrSecretNotFound)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	cs, err := sut.GetContentSecret(id, pass)
+	cs, err := sut.GetContentSecret(context.Background(), id, pass)
 
 	assert.NotNil(t, err)
 	assert.Equal(t, "it either never existed or has already been viewed", err.Error())
@@ -159,11 +203,17 @@ func TestGetContentSecretWithPassRequiredToSeeSecretButMissingPassword(t *testin
 			ExpiredAt: time.Now(),
 		}, nil)
 	mockRepo.
-		On("RemoveSecret", id).
-		Return(nil)
+		On("ClaimSecret", id).
+		Return(Secret{
+			ID:        id,
+			Content:   "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+			CustomPwd: false,
+			CreatedAt: time.Now(),
+			ExpiredAt: time.Now(),
+		}, nil)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	cs, err := sut.GetContentSecret(id, "")
+	cs, err := sut.GetContentSecret(context.Background(), id, "")
 
 	assert.NotNil(t, err)
 	assert.Empty(t, cs)
@@ -193,7 +243,7 @@ func TestGCreateSecretWithoutPasswordAndNoErrors(t *testing.T) {
 
 	mockRepo := new(MockRepository)
 	mockRepo.
-		On("CreateSecret", mock.Anything, customPass, mock.Anything).
+		On("CreateSecret", mock.Anything, customPass, mock.Anything, 1, mock.Anything).
 		Return(Secret{
 			ID:        id,
 			Content:   contentEncrypted,
@@ -203,7 +253,7 @@ func TestGCreateSecretWithoutPasswordAndNoErrors(t *testing.T) {
 		}, nil)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	secret, err := sut.CreateSecret(content, "")
+	secret, err := sut.CreateSecret(context.Background(), content, "", 0, 0)
 
 	assert.Nil(t, err)
 	assert.Equal(t, contentEncrypted, secret.Content)
@@ -217,7 +267,7 @@ func TestCreateSecretErrorEmptyContent(t *testing.T) {
 	mockRepo := new(MockRepository)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	_, err := sut.CreateSecret("", "")
+	_, err := sut.CreateSecret(context.Background(), "", "", 0, 0)
 
 	assert.NotNil(t, err)
 	assert.Equal(t, "empty content", err.Error())
@@ -233,7 +283,7 @@ func TestCreateSecretErrorPasswordTooLong(t *testing.T) {
 	mockRepo := new(MockRepository)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	_, err := sut.CreateSecret(content, passwordTooLong)
+	_, err := sut.CreateSecret(context.Background(), content, passwordTooLong, 0, 0)
 
 	assert.NotNil(t, err)
 	assert.Equal(t, "password too long", err.Error())
@@ -248,8 +298,793 @@ func TestCreateSecretErrorContentTooLong(t *testing.T) {
 	mockRepo := new(MockRepository)
 
 	sut := NewSecretService(mockRepo, key, pass)
-	_, err := sut.CreateSecret(content, "")
+	_, err := sut.CreateSecret(context.Background(), content, "", 0, 0)
 
 	assert.NotNil(t, err)
 	assert.Equal(t, "text too long", err.Error())
 }
+
+func TestCreateSecretAndGetContentSecretWithDerivedKey(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+	content := "this is my secret"
+
+	var stored Secret
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("CreateSecret", mock.Anything, true, mock.Anything, 1, mock.Anything).
+		Run(func(args mock.Arguments) {
+			stored = Secret{ID: id, Content: args.String(0), CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}
+		}).
+		Return(Secret{ID: id}, nil)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	_, err := sut.CreateSecret(context.Background(), content, "1234", 0, 0)
+
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(stored.Content, envelopePrefix))
+	assert.NotContains(t, stored.Content, hex.EncodeToString([]byte(content)))
+
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(true, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(stored, nil)
+	mockRepo.
+		On("ClaimSecret", id).
+		Return(stored, nil)
+
+	cs, err := sut.GetContentSecret(context.Background(), id, "1234")
+
+	assert.Nil(t, err)
+	assert.Equal(t, content, cs.Content)
+}
+
+func TestCreateSecretWithoutPasswordExpandsKey(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	var stored Secret
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("CreateSecret", mock.Anything, false, mock.Anything, 1, mock.Anything).
+		Run(func(args mock.Arguments) {
+			stored = Secret{ID: id, Content: args.String(0), CreatedAt: time.Now(), ExpiredAt: expired}
+		}).
+		Return(Secret{ID: id}, nil)
+
+	// no slot to derive keys, the default password does not need one
+	sut := NewSecretService(mockRepo, key, pass).(*secretService)
+	sut.derivations = make(chan struct{})
+	_, err := sut.CreateSecret(context.Background(), "this is my secret", "", 0, 0)
+
+	assert.Nil(t, err)
+
+	e, err := parseEnvelope(stored.Content)
+
+	assert.Nil(t, err)
+	assert.Equal(t, envelopeV3, e.version)
+
+	content, err := sut.decryptContentSecret(context.Background(), stored.Content, pass)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "this is my secret", content)
+}
+
+func TestGetContentSecretWaitsForDerivationSlot(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	sut := NewSecretService(nil, key, pass, WithMaxConcurrentDerivations(1)).(*secretService)
+	content, err := sut.encryptContentSecret(context.Background(), "this is my secret", "1234", true)
+	assert.Nil(t, err)
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(true, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, Content: content, CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+	sut.repository = mockRepo
+
+	// the only slot is taken by another derivation
+	sut.derivations <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = sut.GetContentSecret(ctx, id, "1234")
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	mockRepo.AssertNotCalled(t, "IncrementFailedAttempts", id)
+	mockRepo.AssertNotCalled(t, "ClaimSecret", id)
+}
+
+func TestGetContentSecretWithDerivedKeyAndWrongPassword(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	sut := NewSecretService(nil, key, pass).(*secretService)
+	content, err := sut.encryptContentSecret(context.Background(), "this is my secret", "1234", true)
+	assert.Nil(t, err)
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(true, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, Content: content, CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+	mockRepo.
+		On("ClaimSecret", id).
+		Return(Secret{ID: id, Content: content, CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+
+	mockRepo.
+		On("IncrementFailedAttempts", id).
+		Return(1, nil)
+
+	sut.repository = mockRepo
+	cs, err := sut.GetContentSecret(context.Background(), id, "12345")
+
+	assert.Empty(t, cs)
+	assert.Equal(t, ErrPassToDecrypt, err)
+	mockRepo.AssertNotCalled(t, "ClaimSecret", id)
+}
+
+func TestDecryptContentSecretUnknownEnvelopeVersion(t *testing.T) {
+
+	sut := NewSecretService(nil, "11111111111111111111111111111111", "@myPassword").(*secretService)
+	_, err := sut.decryptContentSecret(context.Background(), envelopePrefix+"ff00", "")
+
+	assert.Equal(t, ErrUnknownFormat, err)
+}
+
+func TestGetContentSecretSealedWithRetiredKey(t *testing.T) {
+
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	oldKeyring, _ := NewKeyring("old", "11111111111111111111111111111111", nil)
+	content, err := NewSecretServiceWithKeyring(nil, oldKeyring, pass).(*secretService).encryptContentSecret(context.Background(), "this is my secret", "1234", true)
+	assert.Nil(t, err)
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(true, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, Content: content, CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+	mockRepo.
+		On("ClaimSecret", id).
+		Return(Secret{ID: id, Content: content, CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+
+	keyring, _ := NewKeyring("new", "22222222222222222222222222222222", map[string]string{"old": "11111111111111111111111111111111"})
+	sut := NewSecretServiceWithKeyring(mockRepo, keyring, pass)
+	cs, err := sut.GetContentSecret(context.Background(), id, "1234")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "this is my secret", cs.Content)
+
+	keyring, _ = NewKeyring("new", "22222222222222222222222222222222", nil)
+	_, err = NewSecretServiceWithKeyring(nil, keyring, pass).(*secretService).decryptContentSecret(context.Background(), content, "1234")
+
+	assert.Equal(t, ErrUnknownKey, err)
+}
+
+func TestGetContentSecretWithMissingKeyIsNotAWrongPassword(t *testing.T) {
+
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	oldKeyring, _ := NewKeyring("old", "11111111111111111111111111111111", nil)
+	content, err := NewSecretServiceWithKeyring(nil, oldKeyring, pass).(*secretService).encryptContentSecret(context.Background(), "this is my secret", "1234", true)
+	assert.Nil(t, err)
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(true, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, Content: content, CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+
+	// the key "old" is missing, the right password must not count as a wrong one
+	keyring, _ := NewKeyring("new", "22222222222222222222222222222222", nil)
+	sut := NewSecretServiceWithKeyring(mockRepo, keyring, pass)
+	for i := 0; i < DefaultMaxPasswordAttempts+1; i++ {
+		_, err := sut.GetContentSecret(context.Background(), id, "1234")
+
+		assert.Equal(t, ErrUnknownKey, err)
+	}
+
+	mockRepo.AssertNotCalled(t, "IncrementFailedAttempts", id)
+	mockRepo.AssertNotCalled(t, "RemoveSecret", id)
+	mockRepo.AssertNotCalled(t, "ClaimSecret", id)
+}
+
+func TestGetContentSecretWithoutPasswordFailsToDecrypt(t *testing.T) {
+
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	// sealed with the same server key but another default password, e.g. a misconfigured replica
+	content, err := NewSecretService(nil, "11111111111111111111111111111111", "@otherPassword").(*secretService).encryptContentSecret(context.Background(), "this is my secret", "@otherPassword", false)
+	assert.Nil(t, err)
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(false, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, Content: content, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+
+	sut := NewSecretService(mockRepo, "11111111111111111111111111111111", "@myPassword")
+	_, err = sut.GetContentSecret(context.Background(), id, "")
+
+	assert.Equal(t, ErrToDecrypt, err)
+	mockRepo.AssertNotCalled(t, "ClaimSecret", id)
+}
+
+func TestGetContentSecretLegacyWithRetiredKey(t *testing.T) {
+
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(false, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{
+			ID:        id,
+			Content:   "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+			CustomPwd: false,
+			CreatedAt: time.Now(),
+			ExpiredAt: time.Now(),
+		}, nil)
+	mockRepo.
+		On("ClaimSecret", id).
+		Return(Secret{
+			ID:        id,
+			Content:   "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+			CustomPwd: false,
+			CreatedAt: time.Now(),
+			ExpiredAt: time.Now(),
+		}, nil)
+
+	keyring, _ := NewKeyring("new", "22222222222222222222222222222222", map[string]string{"old": "11111111111111111111111111111111"})
+	sut := NewSecretServiceWithKeyring(mockRepo, keyring, pass)
+	cs, err := sut.GetContentSecret(context.Background(), id, "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "My name is Bernie", cs.Content)
+}
+
+func TestRekeySecrets(t *testing.T) {
+
+	pass := "@myPassword"
+	oldKey := "11111111111111111111111111111111"
+
+	oldKeyring, _ := NewKeyring("old", oldKey, nil)
+	old := NewSecretServiceWithKeyring(nil, oldKeyring, pass).(*secretService)
+	sealedWithOldKey, _ := old.encryptContentSecret(context.Background(), "sealed with old key", "1234", true)
+	legacyCustomPwd := "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922"
+
+	keyring, _ := NewKeyring("new", "22222222222222222222222222222222", map[string]string{"old": oldKey})
+	sut := NewSecretServiceWithKeyring(nil, keyring, pass).(*secretService)
+	sealedWithNewKey, _ := sut.encryptContentSecret(context.Background(), "sealed with new key", "1234", true)
+
+	updated := map[string]string{}
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("GetSecrets").
+		Return([]Secret{
+			{ID: "1", Content: sealedWithOldKey, CustomPwd: true},
+			{ID: "2", Content: sealedWithNewKey, CustomPwd: true},
+			{ID: "3", Content: legacyCustomPwd, CustomPwd: false},
+			{ID: "4", Content: legacyCustomPwd, CustomPwd: true},
+		}, nil)
+	mockRepo.
+		On("UpdateSecretContent", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			updated[args.String(0)] = args.String(1)
+		}).
+		Return(nil)
+
+	sut.repository = mockRepo
+	r, err := sut.RekeySecrets(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, RekeyResult{Rekeyed: 2, Skipped: 1}, r)
+	assert.Len(t, updated, 2)
+
+	onlyNewKey, _ := NewKeyring("new", "22222222222222222222222222222222", nil)
+	newOnly := NewSecretServiceWithKeyring(nil, onlyNewKey, pass).(*secretService)
+
+	c1, err1 := newOnly.decryptContentSecret(context.Background(), updated["1"], "1234")
+	c3, err3 := newOnly.decryptContentSecret(context.Background(), updated["3"], pass)
+
+	assert.Nil(t, err1)
+	assert.Equal(t, "sealed with old key", c1)
+	assert.Nil(t, err3)
+	assert.Equal(t, "My name is Bernie", c3)
+
+	e3, _ := parseEnvelope(updated["3"])
+
+	assert.Equal(t, envelopeV3, e3.version)
+}
+
+func TestCreateSecretWithTTL(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	var expire time.Time
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("CreateSecret", mock.Anything, false, mock.Anything, 1, mock.Anything).
+		Run(func(args mock.Arguments) {
+			expire = args.Get(2).(time.Time)
+		}).
+		Return(Secret{ID: id}, nil)
+
+	sut := NewSecretService(mockRepo, key, pass)
+
+	_, err := sut.CreateSecret(context.Background(), "this is my secret", "", time.Hour, 0)
+
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().UTC().Add(time.Hour), expire, time.Minute)
+
+	_, err = sut.CreateSecret(context.Background(), "this is my secret", "", 0, 0)
+
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().UTC().Add(DefaultTTLPolicy.Default), expire, time.Minute)
+}
+
+func TestCreateSecretErrorTTLOutOfPolicy(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+
+	policy, err := NewTTLPolicy(time.Minute, time.Hour, 10*time.Minute)
+	assert.Nil(t, err)
+
+	mockRepo := new(MockRepository)
+
+	sut := NewSecretService(mockRepo, key, pass, WithTTLPolicy(policy))
+	_, err1 := sut.CreateSecret(context.Background(), "this is my secret", "", time.Second, 0)
+	_, err2 := sut.CreateSecret(context.Background(), "this is my secret", "", -time.Hour, 0)
+	_, err3 := sut.CreateSecret(context.Background(), "this is my secret", "", 2*time.Hour, 0)
+
+	assert.Equal(t, ErrTTLTooShort, err1)
+	assert.Equal(t, ErrTTLTooShort, err2)
+	assert.Equal(t, ErrTTLTooLong, err3)
+}
+
+func TestNewTTLPolicyInvalid(t *testing.T) {
+
+	_, err1 := NewTTLPolicy(time.Hour, time.Minute, time.Minute)
+	_, err2 := NewTTLPolicy(time.Minute, time.Hour, 2*time.Hour)
+	_, err3 := NewTTLPolicy(0, time.Hour, time.Minute)
+
+	assert.Equal(t, ErrInvalidTTLPolicy, err1)
+	assert.Equal(t, ErrInvalidTTLPolicy, err2)
+	assert.Equal(t, ErrInvalidTTLPolicy, err3)
+}
+
+func TestGetContentSecretWithRemainingViews(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(false, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{
+			ID:             id,
+			Content:        "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+			CustomPwd:      false,
+			CreatedAt:      time.Now(),
+			ExpiredAt:      time.Now(),
+			RemainingViews: 2,
+		}, nil)
+	mockRepo.
+		On("ClaimSecret", id).
+		Return(Secret{
+			ID:             id,
+			Content:        "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+			CustomPwd:      false,
+			CreatedAt:      time.Now(),
+			ExpiredAt:      time.Now(),
+			RemainingViews: 2,
+		}, nil)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	cs, err := sut.GetContentSecret(context.Background(), id, "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "My name is Bernie", cs.Content)
+	assert.Equal(t, 2, cs.RemainingViews)
+}
+
+func TestCreateSecretWithMaxViews(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("CreateSecret", mock.Anything, false, mock.Anything, 3, mock.Anything).
+		Return(Secret{ID: id, RemainingViews: 3}, nil)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	secret, err := sut.CreateSecret(context.Background(), "this is my secret", "", 0, 3)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, secret.RemainingViews)
+
+	_, err1 := sut.CreateSecret(context.Background(), "this is my secret", "", 0, -1)
+	_, err2 := sut.CreateSecret(context.Background(), "this is my secret", "", 0, 101)
+
+	assert.Equal(t, ErrInvalidMaxViews, err1)
+	assert.Equal(t, ErrInvalidMaxViews, err2)
+}
+
+func TestGetContentSecretWrongPasswordDeletesSecretAfterMaxAttempts(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	sut := NewSecretService(nil, key, pass, WithMaxPasswordAttempts(2)).(*secretService)
+	content, err := sut.encryptContentSecret(context.Background(), "this is my secret", "1234", true)
+	assert.Nil(t, err)
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(true, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, Content: content, CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+	mockRepo.
+		On("IncrementFailedAttempts", id).
+		Return(1, nil).
+		Once()
+	mockRepo.
+		On("IncrementFailedAttempts", id).
+		Return(2, nil).
+		Once()
+	mockRepo.
+		On("RemoveSecret", id).
+		Return(nil)
+
+	sut.repository = mockRepo
+
+	_, err1 := sut.GetContentSecret(context.Background(), id, "12345")
+	mockRepo.AssertNotCalled(t, "RemoveSecret", id)
+
+	_, err2 := sut.GetContentSecret(context.Background(), id, "12345")
+
+	assert.Equal(t, ErrPassToDecrypt, err1)
+	assert.Equal(t, ErrTooManyAttempts, err2)
+	mockRepo.AssertCalled(t, "RemoveSecret", id)
+	mockRepo.AssertNotCalled(t, "ClaimSecret", id)
+}
+
+func TestGetContentSecretClaimedConcurrentlyAfterDecrypt(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+	secret := Secret{
+		ID:        id,
+		Content:   "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+		CustomPwd: false,
+		CreatedAt: time.Now(),
+		ExpiredAt: time.Now(),
+	}
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(false, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(secret, nil)
+	mockRepo.
+		On("ClaimSecret", id).
+		Return(Secret{}, ErrSecretNotFound)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	cs, err := sut.GetContentSecret(context.Background(), id, "")
+
+	assert.Empty(t, cs)
+	assert.Equal(t, ErrSecretNotFound, err)
+}
+
+func TestGetSecretInfoDoesNotReturnContent(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{
+			ID:             id,
+			Content:        "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922",
+			CustomPwd:      true,
+			CreatedAt:      time.Now(),
+			ExpiredAt:      expired,
+			RemainingViews: 3,
+		}, nil)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	info, err := sut.GetSecretInfo(context.Background(), id)
+
+	assert.Nil(t, err)
+	assert.Empty(t, info.Content)
+	assert.True(t, info.CustomPwd)
+	assert.Equal(t, expired, info.ExpiredAt)
+	assert.Equal(t, 3, info.RemainingViews)
+	mockRepo.AssertNotCalled(t, "ClaimSecret", id)
+}
+
+func TestGetSecretInfoButNotExistOrWasViewed(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{}, ErrSecretNotFound)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	_, err := sut.GetSecretInfo(context.Background(), id)
+
+	assert.Equal(t, ErrSecretNotFound, err)
+}
+
+func TestGetSecretInfoRepositoryFails(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+	errRepository := errors.New("dial tcp 127.0.0.1:3306: connect: connection refused")
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{}, errRepository)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	_, err := sut.GetSecretInfo(context.Background(), id)
+
+	assert.Equal(t, errRepository, err)
+}
+
+func TestCreateSecretAndDeleteSecretWithDeletionToken(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	var stored Secret
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("CreateSecret", mock.Anything, false, mock.Anything, 1, mock.Anything).
+		Run(func(args mock.Arguments) {
+			stored = Secret{ID: id, Content: args.String(0), ExpiredAt: expired, RemainingViews: 1, DeletionTokenHash: args.String(4)}
+		}).
+		Return(Secret{ID: id}, nil)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	secret, err := sut.CreateSecret(context.Background(), "this is my secret", "", 0, 0)
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, secret.DeletionToken)
+	assert.NotEqual(t, secret.DeletionToken, stored.DeletionTokenHash)
+
+	mockRepo.
+		On("GetSecret", id).
+		Return(stored, nil)
+	mockRepo.
+		On("RemoveSecret", id).
+		Return(nil)
+
+	err1 := sut.DeleteSecret(context.Background(), id, "wrong token")
+
+	assert.Equal(t, ErrInvalidToken, err1)
+	mockRepo.AssertNotCalled(t, "RemoveSecret", id)
+
+	err2 := sut.DeleteSecret(context.Background(), id, secret.DeletionToken)
+
+	assert.Nil(t, err2)
+	mockRepo.AssertCalled(t, "RemoveSecret", id)
+}
+
+func TestDeleteSecretWithoutDeletionTokenHash(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, ExpiredAt: expired, RemainingViews: 1}, nil)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	err := sut.DeleteSecret(context.Background(), id, "")
+
+	assert.Equal(t, ErrInvalidToken, err)
+	mockRepo.AssertNotCalled(t, "RemoveSecret", id)
+}
+
+func TestGetContentSecretContextCanceled(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(false, context.Canceled)
+
+	sut := NewSecretService(mockRepo, key, pass)
+	_, err := sut.GetContentSecret(context.Background(), id, "")
+
+	assert.Equal(t, context.Canceled, err)
+	mockRepo.AssertNotCalled(t, "ClaimSecret", id)
+}
+
+type deadlineRepository struct {
+	MockRepository
+	deadline time.Time
+}
+
+func (r *deadlineRepository) GetSecret(ctx context.Context, id string) (Secret, error) {
+	r.deadline, _ = ctx.Deadline()
+	return r.MockRepository.GetSecret(ctx, id)
+}
+
+func TestWithStorageTimeout(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	repo := new(deadlineRepository)
+	repo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, ExpiredAt: expired, RemainingViews: 1}, nil)
+
+	sut := NewSecretService(repo, key, pass, WithStorageTimeout(time.Second))
+	_, err := sut.GetSecretInfo(context.Background(), id)
+
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Second), repo.deadline, 100*time.Millisecond)
+}
+
+type recordingSink struct {
+	events []audit.Event
+}
+
+func (s *recordingSink) Record(ctx context.Context, e audit.Event) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *recordingSink) types() []audit.EventType {
+	var types []audit.EventType
+	for _, e := range s.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestSecretLifecycleIsAudited(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	var stored Secret
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("CreateSecret", mock.Anything, false, mock.Anything, 2, mock.Anything).
+		Run(func(args mock.Arguments) {
+			stored = Secret{ID: id, Content: args.String(0), ExpiredAt: expired, RemainingViews: 2, DeletionTokenHash: args.String(4)}
+		}).
+		Return(Secret{ID: id}, nil)
+
+	sink := &recordingSink{}
+	sut := NewSecretService(mockRepo, key, pass, WithAuditSink(sink))
+	secret, err := sut.CreateSecret(context.Background(), "this is my secret", "", 0, 2)
+
+	assert.Nil(t, err)
+
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(false, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(stored, nil)
+	mockRepo.
+		On("ClaimSecret", id).
+		Return(Secret{ID: id, Content: stored.Content, RemainingViews: 1}, nil)
+	mockRepo.
+		On("RemoveSecret", id).
+		Return(nil)
+
+	_, err1 := sut.GetContentSecret(context.Background(), id, "")
+	err2 := sut.DeleteSecret(context.Background(), id, secret.DeletionToken)
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, []audit.EventType{audit.EventCreated, audit.EventViewed, audit.EventDeleted}, sink.types())
+	for _, e := range sink.events {
+		assert.Equal(t, id, e.SecretID)
+		assert.WithinDuration(t, time.Now(), e.Time, time.Minute)
+	}
+}
+
+func TestWrongPasswordsAreAudited(t *testing.T) {
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	sink := &recordingSink{}
+	sut := NewSecretService(nil, key, pass, WithMaxPasswordAttempts(2), WithAuditSink(sink)).(*secretService)
+	content, err := sut.encryptContentSecret(context.Background(), "this is my secret", "1234", true)
+	assert.Nil(t, err)
+
+	mockRepo := new(MockRepository)
+	mockRepo.
+		On("HasSecretWithCustomPwd", id).
+		Return(true, nil)
+	mockRepo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, Content: content, CustomPwd: true, CreatedAt: time.Now(), ExpiredAt: expired}, nil)
+	mockRepo.
+		On("IncrementFailedAttempts", id).
+		Return(1, nil).
+		Once()
+	mockRepo.
+		On("IncrementFailedAttempts", id).
+		Return(2, nil).
+		Once()
+	mockRepo.
+		On("RemoveSecret", id).
+		Return(nil)
+
+	sut.repository = mockRepo
+
+	_, _ = sut.GetContentSecret(context.Background(), id, "12345")
+	_, _ = sut.GetContentSecret(context.Background(), id, "12345")
+
+	assert.Equal(t, []audit.EventType{audit.EventWrongPassword, audit.EventWrongPassword, audit.EventLockedOut}, sink.types())
+}
@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/auth"
+	"github.com/bernardosecades/sharesecret/internal/ratelimit"
+
+	"github.com/golang/protobuf/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the reasons in the errdetails.ErrorInfo of the errors
+const ErrorDomain = "sharesecret"
+
+// Machine-readable reasons of the errors, sent in an errdetails.ErrorInfo
+const (
+	ReasonSecretNotFound       = "SECRET_NOT_FOUND"
+	ReasonPasswordRequired     = "PASSWORD_REQUIRED"
+	ReasonPasswordNotRequired  = "PASSWORD_NOT_REQUIRED"
+	ReasonWrongPassword        = "WRONG_PASSWORD"
+	ReasonTooManyAttempts      = "TOO_MANY_ATTEMPTS"
+	ReasonInvalidDeletionToken = "INVALID_DELETION_TOKEN"
+	ReasonEmptyContent         = "EMPTY_CONTENT"
+	ReasonContentTooLong       = "CONTENT_TOO_LONG"
+	ReasonPasswordTooLong      = "PASSWORD_TOO_LONG"
+	ReasonTTLTooShort          = "TTL_TOO_SHORT"
+	ReasonTTLTooLong           = "TTL_TOO_LONG"
+	ReasonInvalidMaxViews      = "INVALID_MAX_VIEWS"
+	ReasonMissingCredentials   = "MISSING_CREDENTIALS"
+	ReasonInvalidCredentials   = "INVALID_CREDENTIALS"
+	ReasonRateLimited          = "RATE_LIMITED"
+	ReasonCredentialsInURL     = "CREDENTIALS_IN_URL"
+	ReasonCanceled             = "CANCELED"
+	ReasonDeadlineExceeded     = "DEADLINE_EXCEEDED"
+	ReasonInternal             = "INTERNAL"
+)
+
+// errInternal replaces the message of the errors not known by the clients (e.g. storage failures)
+var errInternal = errors.New("internal error")
+
+// errorStatuses are the code and reason of each known error, the gateway maps the codes to HTTP statuses
+// (NotFound 404, Unauthenticated 401, PermissionDenied 403, InvalidArgument 400, ResourceExhausted 429, Internal 500)
+var errorStatuses = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{sharesecret.ErrSecretNotFound, codes.NotFound, ReasonSecretNotFound},
+	{sharesecret.ErrMissingPass, codes.Unauthenticated, ReasonPasswordRequired},
+	{sharesecret.ErrPassToDecrypt, codes.Unauthenticated, ReasonWrongPassword},
+	{auth.ErrMissingCredentials, codes.Unauthenticated, ReasonMissingCredentials},
+	{auth.ErrInvalidCredentials, codes.Unauthenticated, ReasonInvalidCredentials},
+	{sharesecret.ErrTooManyAttempts, codes.PermissionDenied, ReasonTooManyAttempts},
+	{sharesecret.ErrInvalidToken, codes.PermissionDenied, ReasonInvalidDeletionToken},
+	{sharesecret.ErrNoPassRequired, codes.InvalidArgument, ReasonPasswordNotRequired},
+	{sharesecret.ErrEmptyContent, codes.InvalidArgument, ReasonEmptyContent},
+	{sharesecret.ErrTextTooLong, codes.InvalidArgument, ReasonContentTooLong},
+	{sharesecret.ErrPassTooLong, codes.InvalidArgument, ReasonPasswordTooLong},
+	{sharesecret.ErrTTLTooShort, codes.InvalidArgument, ReasonTTLTooShort},
+	{sharesecret.ErrTTLTooLong, codes.InvalidArgument, ReasonTTLTooLong},
+	{sharesecret.ErrInvalidMaxViews, codes.InvalidArgument, ReasonInvalidMaxViews},
+	{ratelimit.ErrLimitExceeded, codes.ResourceExhausted, ReasonRateLimited},
+	{context.Canceled, codes.Canceled, ReasonCanceled},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, ReasonDeadlineExceeded},
+}
+
+// errorStatus returns the status of the error with its reason, unknown errors are logged and returned as
+// Internal without their message because they can expose details of the storage. details are sent after the reason.
+func errorStatus(err error, details ...proto.Message) error {
+
+	code, reason, msg := codes.Internal, ReasonInternal, errInternal.Error()
+	for _, s := range errorStatuses {
+		if errors.Is(err, s.err) {
+			code, reason, msg = s.code, s.reason, err.Error()
+			break
+		}
+	}
+
+	if code == codes.Internal {
+		log.Printf("Internal error: %v\n", err)
+	}
+
+	st, detailsErr := status.New(code, msg).WithDetails(append([]proto.Message{&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}}, details...)...)
+	if detailsErr != nil {
+		return status.Error(code, msg)
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,54 @@
+// +build unit
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorStatus(t *testing.T) {
+
+	tests := []struct {
+		err    error
+		code   codes.Code
+		reason string
+		msg    string
+	}{
+		{sharesecret.ErrSecretNotFound, codes.NotFound, ReasonSecretNotFound, sharesecret.ErrSecretNotFound.Error()},
+		{sharesecret.ErrMissingPass, codes.Unauthenticated, ReasonPasswordRequired, sharesecret.ErrMissingPass.Error()},
+		{sharesecret.ErrPassToDecrypt, codes.Unauthenticated, ReasonWrongPassword, sharesecret.ErrPassToDecrypt.Error()},
+		{sharesecret.ErrTooManyAttempts, codes.PermissionDenied, ReasonTooManyAttempts, sharesecret.ErrTooManyAttempts.Error()},
+		{sharesecret.ErrInvalidToken, codes.PermissionDenied, ReasonInvalidDeletionToken, sharesecret.ErrInvalidToken.Error()},
+		{sharesecret.ErrEmptyContent, codes.InvalidArgument, ReasonEmptyContent, sharesecret.ErrEmptyContent.Error()},
+		{sharesecret.ErrTTLTooLong, codes.InvalidArgument, ReasonTTLTooLong, sharesecret.ErrTTLTooLong.Error()},
+		{fmt.Errorf("get secret: %w", context.DeadlineExceeded), codes.DeadlineExceeded, ReasonDeadlineExceeded, "get secret: context deadline exceeded"},
+		{context.Canceled, codes.Canceled, ReasonCanceled, context.Canceled.Error()},
+		{errors.New("Error 1045: Access denied for user 'root'@'172.18.0.1'"), codes.Internal, ReasonInternal, "internal error"},
+		{sharesecret.ErrToEncrypt, codes.Internal, ReasonInternal, "internal error"},
+		{sharesecret.ErrToDecrypt, codes.Internal, ReasonInternal, "internal error"},
+		{sharesecret.ErrUnknownKey, codes.Internal, ReasonInternal, "internal error"},
+	}
+
+	for _, tt := range tests {
+		st := status.Convert(errorStatus(tt.err))
+
+		assert.Equal(t, tt.code, st.Code(), tt.err.Error())
+		assert.Equal(t, tt.msg, st.Message())
+		if assert.Len(t, st.Details(), 1) {
+			info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+			assert.True(t, ok)
+			assert.Equal(t, tt.reason, info.GetReason())
+			assert.Equal(t, ErrorDomain, info.GetDomain())
+		}
+	}
+}
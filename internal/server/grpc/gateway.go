@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// GatewayHeader is the metadata with which the REST gateway of the process authenticates its calls, so the server
+// can rely on what the gateway forwards (the IP of its client) and on what it forbids (credentials in the URL)
+const GatewayHeader = "sharesecret-gateway"
+
+// gatewayToken is random by process, only the gateway running in the process knows it
+var gatewayToken = newGatewayToken()
+
+func newGatewayToken() string {
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// GatewayMetadata returns the metadata the REST gateway of the process adds to its calls
+func GatewayMetadata() metadata.MD {
+	return metadata.Pairs(GatewayHeader, gatewayToken)
+}
+
+// fromGateway reports whether the call comes from the REST gateway of the process
+func fromGateway(ctx context.Context) bool {
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(GatewayHeader) {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(gatewayToken)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,14 +3,11 @@ package grpc
 import (
 	"context"
 	"errors"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 
 	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
 	sharesecret "github.com/bernardosecades/sharesecret/internal"
-	_ "google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	_ "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type shareSecretHandler struct {
@@ -23,13 +20,15 @@ func NewShareSecretServer(s sharesecret.SecretService) sharesecretgrpc.SecretSer
 
 func (s shareSecretHandler) CreateSecret(ctx context.Context, req *sharesecretgrpc.CreateSecretRequest) (*sharesecretgrpc.CreateSecretResponse, error) {
 
-	secret, err := s.secretService.CreateSecret(req.Content, req.Password)
+	secret, err := s.secretService.CreateSecret(ctx, req.Content, req.Password, req.GetTtl().AsDuration(), int(req.GetMaxViews()))
 	if err != nil {
-		return nil, status.New(codes.InvalidArgument, err.Error()).Err()
+		return nil, errorStatus(err)
 	}
 
 	r := &sharesecretgrpc.CreateSecretResponse{}
 	r.Id = secret.ID
+	r.ExpiredAt = timestamppb.New(secret.ExpiredAt)
+	r.DeletionToken = secret.DeletionToken
 
 	return r, nil
 }
@@ -39,7 +38,7 @@ func (s shareSecretHandler) SeeSecret(ctx context.Context, req *sharesecretgrpc.
 	reqHeaders, ok := metadata.FromIncomingContext(ctx) // In postman su need put prefix: grpc-metadata-{yourHeaderName}. Example: grpc-metadata-password
 
 	if !ok {
-		return nil, errors.New("Error context")
+		return nil, errorStatus(errors.New("Error context"))
 	}
 
 	var password = ""
@@ -49,14 +48,57 @@ func (s shareSecretHandler) SeeSecret(ctx context.Context, req *sharesecretgrpc.
 		password = req.Password
 	}
 
-	c, err := s.secretService.GetContentSecret(req.Id, password)
+	secret, err := s.secretService.GetContentSecret(ctx, req.Id, password)
 
 	if err != nil {
-		return nil, status.New(codes.InvalidArgument, err.Error()).Err()
+		return nil, errorStatus(err)
 	}
 
 	r := &sharesecretgrpc.SeeSecretResponse{}
-	r.Content = c
+	r.Content = secret.Content
+	r.RemainingViews = int32(secret.RemainingViews)
 
 	return r, nil
 }
+
+func (s shareSecretHandler) GetSecretInfo(ctx context.Context, req *sharesecretgrpc.GetSecretInfoRequest) (*sharesecretgrpc.GetSecretInfoResponse, error) {
+
+	secret, err := s.secretService.GetSecretInfo(ctx, req.Id)
+
+	if err == sharesecret.ErrSecretNotFound {
+		return &sharesecretgrpc.GetSecretInfoResponse{Exists: false}, nil
+	}
+
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+
+	r := &sharesecretgrpc.GetSecretInfoResponse{}
+	r.Exists = true
+	r.CustomPwd = secret.CustomPwd
+	r.CreatedAt = timestamppb.New(secret.CreatedAt)
+	r.ExpiredAt = timestamppb.New(secret.ExpiredAt)
+	r.RemainingViews = int32(secret.RemainingViews)
+
+	return r, nil
+}
+
+func (s shareSecretHandler) DeleteSecret(ctx context.Context, req *sharesecretgrpc.DeleteSecretRequest) (*sharesecretgrpc.DeleteSecretResponse, error) {
+
+	// the REST clients send it as header so it is not in the URL, the gateway would take the field from the query
+	token := req.DeletionToken
+	if fromGateway(ctx) {
+		token = ""
+	}
+	if reqHeaders, ok := metadata.FromIncomingContext(ctx); ok && len(reqHeaders["deletion-token"]) > 0 {
+		token = reqHeaders["deletion-token"][0]
+	}
+
+	err := s.secretService.DeleteSecret(ctx, req.Id, token)
+
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+
+	return &sharesecretgrpc.DeleteSecretResponse{}, nil
+}
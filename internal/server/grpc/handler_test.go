@@ -4,18 +4,26 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/bernardosecades/sharesecret/cmd"
 	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
 	sharesecret "github.com/bernardosecades/sharesecret/internal"
-	"github.com/bernardosecades/sharesecret/internal/storage/mysql"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 const bufSize = 1024 * 1024
@@ -27,16 +35,14 @@ func init() {
 	lis = bufconn.Listen(bufSize)
 	s := grpc.NewServer()
 
-	dbName := os.Getenv("DB_NAME")
-	dbPass := os.Getenv("DB_PASS")
-	dbUser := os.Getenv("DB_USER")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-
 	secretKey := os.Getenv("SECRET_KEY")
 	secretPassword := os.Getenv("SECRET_PASSWORD")
 
-	secretRepository := mysql.NewMySQLSecretRepository(dbName, dbUser, dbPass, dbHost, dbPort)
+	// storage selected as in the server, SHARESECRET_STORAGE=memory runs the suite without database
+	secretRepository, err := cmd.NewSecretRepositoryFromEnv(context.Background())
+	if err != nil {
+		log.Fatalf("Error to load storage: %v", err)
+	}
 	secretService := sharesecret.NewSecretService(secretRepository, secretKey, secretPassword)
 
 	sharesecretgrpc.RegisterSecretServiceServer(s, NewShareSecretServer(secretService))
@@ -108,3 +114,269 @@ func TestCreateAndSeeSecretWithPassword(t *testing.T) {
 	assert.Nil(t, resp3)
 	assert.NotNil(t, err3)
 }
+
+func TestSeeSecretWithWrongPasswordDoesNotDeleteIt(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+	resp1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret", Password: "1234"})
+	if err1 != nil {
+		t.Fatalf("CreateSecret failed: %v", err1)
+	}
+
+	resp2, err2 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId(), Password: "12345"})
+
+	assert.Nil(t, resp2)
+	assert.NotNil(t, err2)
+
+	resp3, err3 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId(), Password: "1234"})
+	if err3 != nil {
+		t.Fatalf("SeeSecret failed: %v", err3)
+	}
+
+	assert.Equal(t, "This is my secret", resp3.GetContent())
+}
+
+func TestCreateSecretWithTTL(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+	resp1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret", Ttl: durationpb.New(time.Hour)})
+	if err1 != nil {
+		t.Fatalf("CreateSecret failed: %v", err1)
+	}
+
+	assert.Len(t, resp1.GetId(), 36)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), resp1.GetExpiredAt().AsTime(), time.Minute)
+
+	resp2, err2 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret", Ttl: durationpb.New(time.Second)})
+
+	assert.Nil(t, resp2)
+	assert.NotNil(t, err2)
+}
+
+func TestCreateAndSeeSecretWithMaxViews(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+	resp1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret", MaxViews: 2})
+	if err1 != nil {
+		t.Fatalf("CreateSecret failed: %v", err1)
+	}
+
+	resp2, err2 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId()})
+	if err2 != nil {
+		t.Fatalf("SeeSecret failed: %v", err2)
+	}
+
+	assert.Equal(t, "This is my secret", resp2.GetContent())
+	assert.Equal(t, int32(1), resp2.GetRemainingViews())
+
+	resp3, err3 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId()})
+	if err3 != nil {
+		t.Fatalf("SeeSecret failed: %v", err3)
+	}
+
+	assert.Equal(t, "This is my secret", resp3.GetContent())
+	assert.Equal(t, int32(0), resp3.GetRemainingViews())
+
+	resp4, err4 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId()})
+
+	assert.Nil(t, resp4)
+	assert.NotNil(t, err4)
+}
+
+func TestSeeSecretConcurrentlyOnlyOneSucceeds(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+	resp1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret"})
+	if err1 != nil {
+		t.Fatalf("CreateSecret failed: %v", err1)
+	}
+
+	const readers = 10
+	var wg sync.WaitGroup
+	var seen int32
+
+	wg.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer wg.Done()
+			if resp, err := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId()}); err == nil && resp.GetContent() == "This is my secret" {
+				atomic.AddInt32(&seen, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), seen)
+}
+
+func TestGetSecretInfoDoesNotConsumeSecret(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+	resp1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret", Password: "1234", MaxViews: 2})
+	if err1 != nil {
+		t.Fatalf("CreateSecret failed: %v", err1)
+	}
+
+	resp2, err2 := client.GetSecretInfo(ctx, &sharesecretgrpc.GetSecretInfoRequest{Id: resp1.GetId()})
+	if err2 != nil {
+		t.Fatalf("GetSecretInfo failed: %v", err2)
+	}
+
+	assert.True(t, resp2.GetExists())
+	assert.True(t, resp2.GetCustomPwd())
+	assert.Equal(t, int32(2), resp2.GetRemainingViews())
+	assert.Equal(t, resp1.GetExpiredAt().AsTime().Unix(), resp2.GetExpiredAt().AsTime().Unix())
+
+	resp3, err3 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId(), Password: "1234"})
+	if err3 != nil {
+		t.Fatalf("SeeSecret failed: %v", err3)
+	}
+
+	assert.Equal(t, int32(1), resp3.GetRemainingViews())
+
+	resp4, err4 := client.GetSecretInfo(ctx, &sharesecretgrpc.GetSecretInfoRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd"})
+	if err4 != nil {
+		t.Fatalf("GetSecretInfo failed: %v", err4)
+	}
+
+	assert.False(t, resp4.GetExists())
+}
+
+func TestDeleteSecretWithDeletionToken(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+	resp1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret"})
+	if err1 != nil {
+		t.Fatalf("CreateSecret failed: %v", err1)
+	}
+
+	assert.NotEmpty(t, resp1.GetDeletionToken())
+
+	resp2, err2 := client.DeleteSecret(ctx, &sharesecretgrpc.DeleteSecretRequest{Id: resp1.GetId(), DeletionToken: "wrong token"})
+
+	assert.Nil(t, resp2)
+	assert.NotNil(t, err2)
+
+	_, err3 := client.DeleteSecret(ctx, &sharesecretgrpc.DeleteSecretRequest{Id: resp1.GetId(), DeletionToken: resp1.GetDeletionToken()})
+	if err3 != nil {
+		t.Fatalf("DeleteSecret failed: %v", err3)
+	}
+
+	resp4, err4 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId()})
+
+	assert.Nil(t, resp4)
+	assert.NotNil(t, err4)
+}
+
+func TestDeleteSecretWithDeletionTokenInMetadata(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+	resp1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret"})
+	if err1 != nil {
+		t.Fatalf("CreateSecret failed: %v", err1)
+	}
+
+	// as the gateway forwards the header Grpc-Metadata-Deletion-Token
+	mdCtx := metadata.AppendToOutgoingContext(ctx, "deletion-token", resp1.GetDeletionToken())
+	_, err2 := client.DeleteSecret(mdCtx, &sharesecretgrpc.DeleteSecretRequest{Id: resp1.GetId()})
+	if err2 != nil {
+		t.Fatalf("DeleteSecret failed: %v", err2)
+	}
+
+	resp3, err3 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: resp1.GetId()})
+
+	assert.Nil(t, resp3)
+	assert.NotNil(t, err3)
+}
+
+func TestDeleteSecretFromGatewayIgnoresDeletionTokenField(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+	resp1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret"})
+	if err1 != nil {
+		t.Fatalf("CreateSecret failed: %v", err1)
+	}
+
+	// the gateway fills the field from the query, its calls only take the token from the header
+	gatewayCtx := metadata.NewOutgoingContext(ctx, GatewayMetadata())
+	_, err2 := client.DeleteSecret(gatewayCtx, &sharesecretgrpc.DeleteSecretRequest{Id: resp1.GetId(), DeletionToken: resp1.GetDeletionToken()})
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err2))
+
+	resp3, err3 := client.GetSecretInfo(ctx, &sharesecretgrpc.GetSecretInfoRequest{Id: resp1.GetId()})
+
+	assert.Nil(t, err3)
+	assert.True(t, resp3.GetExists())
+}
+
+// failingRepository fails as a storage that is down
+type failingRepository struct {
+	sharesecret.SecretRepository
+}
+
+func (failingRepository) GetSecret(context.Context, string) (sharesecret.Secret, error) {
+	return sharesecret.Secret{}, errors.New("dial tcp 127.0.0.1:3306: connect: connection refused")
+}
+
+func (failingRepository) HasSecretWithCustomPwd(context.Context, string) (bool, error) {
+	return false, errors.New("dial tcp 127.0.0.1:3306: connect: connection refused")
+}
+
+func TestRepositoryFailsIsInternal(t *testing.T) {
+	ctx := context.Background()
+	handler := NewShareSecretServer(sharesecret.NewSecretService(failingRepository{}, os.Getenv("SECRET_KEY"), os.Getenv("SECRET_PASSWORD")))
+
+	resp1, err1 := handler.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd"})
+
+	assert.Nil(t, resp1)
+	assert.Equal(t, codes.Internal, status.Code(err1))
+
+	resp2, err2 := handler.GetSecretInfo(ctx, &sharesecretgrpc.GetSecretInfoRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd"})
+
+	assert.Nil(t, resp2)
+	assert.Equal(t, codes.Internal, status.Code(err2))
+
+	_, err3 := handler.DeleteSecret(ctx, &sharesecretgrpc.DeleteSecretRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd", DeletionToken: "token"})
+
+	assert.Equal(t, codes.Internal, status.Code(err3))
+}
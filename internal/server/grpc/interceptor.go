@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bernardosecades/sharesecret/internal/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// CreateSecretMethod is the full name of the method creating secrets
+const CreateSecretMethod = "/sharesecret.SecretService/CreateSecret"
+
+// AuthInterceptor returns an interceptor authenticating the calls to methods, the credential is sent as
+// "authorization: Bearer <API key or JWT>" or "x-api-key: <API key>" metadata. The calls to other methods
+// (e.g. SeeSecret by the link recipients) are anonymous.
+func AuthInterceptor(a auth.Authenticator, methods ...string) grpc.UnaryServerInterceptor {
+
+	protected := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		protected[m] = true
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		if !protected[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		p, err := a.Authenticate(credential(ctx))
+		if err != nil {
+			return nil, errorStatus(err)
+		}
+
+		return handler(auth.NewContext(ctx, p), req)
+	}
+}
+
+func credential(ctx context.Context) string {
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if v := md.Get("authorization"); len(v) > 0 {
+		const bearer = "bearer "
+		if len(v[0]) > len(bearer) && strings.EqualFold(v[0][:len(bearer)], bearer) {
+			return strings.TrimSpace(v[0][len(bearer):])
+		}
+	}
+
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,93 @@
+// +build unit
+
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bernardosecades/sharesecret/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAuthenticator accepts the credential "valid"
+type fakeAuthenticator struct{}
+
+func (fakeAuthenticator) Authenticate(credential string) (auth.Principal, error) {
+	switch credential {
+	case "":
+		return auth.Principal{}, auth.ErrMissingCredentials
+	case "valid":
+		return auth.Principal{Name: "frontend", Method: auth.MethodAPIKey}, nil
+	default:
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+}
+
+func callIntercepted(method string, md metadata.MD) (auth.Principal, bool, error) {
+
+	var p auth.Principal
+	var authenticated bool
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		p, authenticated = auth.FromContext(ctx)
+		return nil, nil
+	}
+
+	interceptor := AuthInterceptor(fakeAuthenticator{}, CreateSecretMethod)
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+
+	return p, authenticated, err
+}
+
+func TestAuthInterceptorAuthenticatesProtectedMethods(t *testing.T) {
+
+	p1, ok1, err1 := callIntercepted(CreateSecretMethod, metadata.Pairs("authorization", "Bearer valid"))
+
+	assert.Nil(t, err1)
+	assert.True(t, ok1)
+	assert.Equal(t, "frontend", p1.Name)
+
+	p2, ok2, err2 := callIntercepted(CreateSecretMethod, metadata.Pairs("x-api-key", "valid"))
+
+	assert.Nil(t, err2)
+	assert.True(t, ok2)
+	assert.Equal(t, "frontend", p2.Name)
+}
+
+func TestAuthInterceptorRejectsMissingOrInvalidCredentials(t *testing.T) {
+
+	tests := []struct {
+		md     metadata.MD
+		reason string
+	}{
+		{metadata.MD{}, ReasonMissingCredentials},
+		{metadata.Pairs("authorization", "Basic dmFsaWQ="), ReasonMissingCredentials},
+		{metadata.Pairs("authorization", "Bearer invalid"), ReasonInvalidCredentials},
+		{metadata.Pairs("x-api-key", "invalid"), ReasonInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		_, _, err := callIntercepted(CreateSecretMethod, tt.md)
+		st := status.Convert(err)
+
+		assert.Equal(t, codes.Unauthenticated, st.Code())
+		if assert.Len(t, st.Details(), 1) {
+			assert.Equal(t, tt.reason, st.Details()[0].(*errdetails.ErrorInfo).GetReason())
+		}
+	}
+}
+
+func TestAuthInterceptorLetsOtherMethodsAnonymous(t *testing.T) {
+
+	_, ok, err := callIntercepted("/sharesecret.SecretService/SeeSecret", metadata.MD{})
+
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingInterceptor returns an interceptor logging every call with its method, status code, reason, latency,
+// peer IP and trace ID when it is traced. The requests and responses are never logged, they have the content and
+// the passwords of the secrets.
+func LoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		st := status.Convert(err)
+		fields := []zap.Field{
+			zap.String("method", info.FullMethod),
+			zap.String("code", st.Code().String()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("peer", peerIP(ctx, nil)),
+		}
+
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+		}
+
+		for _, d := range st.Details() {
+			if info, ok := d.(*errdetails.ErrorInfo); ok {
+				fields = append(fields, zap.String("reason", info.GetReason()))
+			}
+		}
+
+		switch st.Code() {
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			logger.Error("call", fields...)
+		default:
+			logger.Info("call", fields...)
+		}
+
+		return resp, err
+	}
+}
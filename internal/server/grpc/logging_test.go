@@ -0,0 +1,57 @@
+// +build unit
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestLoggingInterceptorLogsCallsWithoutRequests(t *testing.T) {
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	interceptor := LoggingInterceptor(zap.New(core))
+
+	ctx := peerContext("10.0.0.1", metadata.MD{})
+	info := &grpc.UnaryServerInfo{FullMethod: "/sharesecret.SecretService/SeeSecret"}
+	req := &sharesecretgrpc.SeeSecretRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd"}
+
+	_, _ = interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &sharesecretgrpc.SeeSecretResponse{Content: "this is my secret"}, nil
+	})
+	_, _ = interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errorStatus(sharesecret.ErrPassToDecrypt)
+	})
+
+	if assert.Equal(t, 2, logs.Len()) {
+		ok := logs.All()[0].ContextMap()
+
+		assert.Equal(t, "/sharesecret.SecretService/SeeSecret", ok["method"])
+		assert.Equal(t, "OK", ok["code"])
+		assert.Equal(t, "10.0.0.1", ok["peer"])
+		assert.Contains(t, ok, "latency")
+
+		failed := logs.All()[1].ContextMap()
+
+		assert.Equal(t, "Unauthenticated", failed["code"])
+		assert.Equal(t, ReasonWrongPassword, failed["reason"])
+	}
+
+	for _, l := range logs.All() {
+		for _, v := range l.ContextMap() {
+			assert.NotContains(t, fmt.Sprint(v), "this is my secret")
+			assert.NotContains(t, fmt.Sprint(v), "727d7040-aac7-4dc3-ab44-938bfba92ebd")
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsInterceptor returns an interceptor counting the calls and observing their latency by service, method
+// and status code in metrics registered in reg (grpc_server_handled_total and grpc_server_handling_seconds)
+func MetricsInterceptor(reg prometheus.Registerer) grpc.UnaryServerInterceptor {
+
+	labels := []string{"grpc_service", "grpc_method", "grpc_code"}
+	handled := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Calls completed on the server by status code.",
+	}, labels)
+	handling := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Latency of the calls handled by the server by status code.",
+		Buckets: prometheus.DefBuckets,
+	}, labels)
+	reg.MustRegister(handled, handling)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		service, method := splitMethod(info.FullMethod)
+		code := status.Code(err).String()
+		handled.WithLabelValues(service, method, code).Inc()
+		handling.WithLabelValues(service, method, code).Observe(time.Since(start).Seconds())
+
+		return resp, err
+	}
+}
+
+// splitMethod splits "/sharesecret.SecretService/SeeSecret" in "sharesecret.SecretService" and "SeeSecret"
+func splitMethod(fullMethod string) (string, string) {
+
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.Index(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+
+	return "unknown", fullMethod
+}
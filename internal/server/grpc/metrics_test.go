@@ -0,0 +1,46 @@
+// +build unit
+
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestMetricsInterceptorCountsCallsByMethodAndCode(t *testing.T) {
+
+	reg := prometheus.NewRegistry()
+	interceptor := MetricsInterceptor(reg)
+	info := &grpc.UnaryServerInfo{FullMethod: "/sharesecret.SecretService/SeeSecret"}
+
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	notFound := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errorStatus(sharesecret.ErrSecretNotFound)
+	}
+
+	_, _ = interceptor(context.Background(), nil, info, ok)
+	_, _ = interceptor(context.Background(), nil, info, ok)
+	_, _ = interceptor(context.Background(), nil, info, notFound)
+
+	err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP grpc_server_handled_total Calls completed on the server by status code.
+# TYPE grpc_server_handled_total counter
+grpc_server_handled_total{grpc_code="NotFound",grpc_method="SeeSecret",grpc_service="sharesecret.SecretService"} 1
+grpc_server_handled_total{grpc_code="OK",grpc_method="SeeSecret",grpc_service="sharesecret.SecretService"} 2
+`), "grpc_server_handled_total")
+
+	assert.Nil(t, err)
+
+	n, err := testutil.GatherAndCount(reg, "grpc_server_handling_seconds")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+}
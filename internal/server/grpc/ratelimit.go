@@ -0,0 +1,152 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bernardosecades/sharesecret/internal/ratelimit"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RetryAfterHeader is the header with the seconds to wait before retrying a rate limited call
+const RetryAfterHeader = "retry-after"
+
+// RateLimitInterceptor returns an interceptor limiting the calls by client IP with ipLimit and, for the calls to
+// a secret (SeeSecret, GetSecretInfo and DeleteSecret), by secret ID with secretLimit so a password can not be
+// brute forced from many IPs. The client IP is taken from "x-forwarded-for" only when the call comes from the
+// gateway of the process or from trustedProxies. Rejected calls return ResourceExhausted with the seconds to
+// wait in the "retry-after" header and an errdetails.RetryInfo.
+func RateLimitInterceptor(store ratelimit.Store, ipLimit ratelimit.Limit, secretLimit ratelimit.Limit, trustedProxies []*net.IPNet) grpc.UnaryServerInterceptor {
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		if ipLimit.Enabled() {
+			if err := take(ctx, store, "ip:"+peerIP(ctx, trustedProxies), ipLimit); err != nil {
+				return nil, err
+			}
+		}
+
+		if r, ok := req.(interface{ GetId() string }); ok && secretLimit.Enabled() && len(r.GetId()) > 0 {
+			if err := take(ctx, store, "secret:"+r.GetId(), secretLimit); err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func take(ctx context.Context, store ratelimit.Store, key string, limit ratelimit.Limit) error {
+
+	ok, retryAfter, err := store.Take(ctx, key, limit)
+	if err != nil {
+		// a failing store does not make the service unavailable
+		log.Printf("Error to take a token of %s: %v\n", key, err)
+		return nil
+	}
+
+	if ok {
+		return nil
+	}
+
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.FormatInt(seconds, 10)))
+
+	return errorStatus(ratelimit.ErrLimitExceeded, &errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(seconds) * time.Second)})
+}
+
+// ParseTrustedProxies parses the IPs and CIDRs (e.g. "10.0.0.1,10.1.0.0/16") of the proxies trusted to set
+// "x-forwarded-for"
+func ParseTrustedProxies(s string) ([]*net.IPNet, error) {
+
+	var proxies []*net.IPNet
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if len(v) == 0 {
+			continue
+		}
+
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", v)
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", v, err)
+		}
+		proxies = append(proxies, n)
+	}
+
+	return proxies, nil
+}
+
+// peerIP returns the IP of the client of the call. The gateway of the process and the trustedProxies append
+// the IP of their client to "x-forwarded-for", so it is walked from the right skipping the trusted proxies.
+// Any other peer could choose the header, its own IP is returned.
+func peerIP(ctx context.Context, trustedProxies []*net.IPNet) string {
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	if !fromGateway(ctx) && !trusted(ip, trustedProxies) {
+		return ip
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var fwd []string
+	for _, v := range md.Get("x-forwarded-for") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); len(f) > 0 {
+				fwd = append(fwd, f)
+			}
+		}
+	}
+
+	for i := len(fwd) - 1; i >= 0; i-- {
+		ip = fwd[i]
+		if !trusted(ip, trustedProxies) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func trusted(ip string, trustedProxies []*net.IPNet) bool {
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, n := range trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
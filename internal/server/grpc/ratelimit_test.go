@@ -0,0 +1,161 @@
+// +build unit
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
+	"github.com/bernardosecades/sharesecret/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func peerContext(ip string, md metadata.MD) context.Context {
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000}})
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func callRateLimited(interceptor grpc.UnaryServerInterceptor, ctx context.Context, req interface{}) error {
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/sharesecret.SecretService/SeeSecret"}, handler)
+
+	return err
+}
+
+func TestRateLimitInterceptorLimitsByIP(t *testing.T) {
+
+	ipLimit, _ := ratelimit.ParseLimit("2/1m")
+	interceptor := RateLimitInterceptor(ratelimit.NewMemoryStore(time.Hour), ipLimit, ratelimit.Limit{}, nil)
+
+	ctx := peerContext("10.0.0.1", metadata.MD{})
+	req := &sharesecretgrpc.SeeSecretRequest{Id: "a"}
+
+	assert.Nil(t, callRateLimited(interceptor, ctx, req))
+	assert.Nil(t, callRateLimited(interceptor, ctx, req))
+
+	st := status.Convert(callRateLimited(interceptor, ctx, req))
+
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	if assert.Len(t, st.Details(), 2) {
+		assert.Equal(t, ReasonRateLimited, st.Details()[0].(*errdetails.ErrorInfo).GetReason())
+		assert.Equal(t, 30*time.Second, st.Details()[1].(*errdetails.RetryInfo).GetRetryDelay().AsDuration())
+	}
+
+	// other IPs have their own limit
+	assert.Nil(t, callRateLimited(interceptor, peerContext("10.0.0.2", metadata.MD{}), req))
+}
+
+func TestRateLimitInterceptorLimitsBySecret(t *testing.T) {
+
+	secretLimit, _ := ratelimit.ParseLimit("1/1m")
+	interceptor := RateLimitInterceptor(ratelimit.NewMemoryStore(time.Hour), ratelimit.Limit{}, secretLimit, nil)
+
+	assert.Nil(t, callRateLimited(interceptor, peerContext("10.0.0.1", metadata.MD{}), &sharesecretgrpc.SeeSecretRequest{Id: "a"}))
+
+	// the same secret from other IP
+	err := callRateLimited(interceptor, peerContext("10.0.0.2", metadata.MD{}), &sharesecretgrpc.SeeSecretRequest{Id: "a"})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	assert.Nil(t, callRateLimited(interceptor, peerContext("10.0.0.2", metadata.MD{}), &sharesecretgrpc.SeeSecretRequest{Id: "b"}))
+	assert.Nil(t, callRateLimited(interceptor, peerContext("10.0.0.2", metadata.MD{}), &sharesecretgrpc.CreateSecretRequest{}))
+	assert.Nil(t, callRateLimited(interceptor, peerContext("10.0.0.2", metadata.MD{}), &sharesecretgrpc.CreateSecretRequest{}))
+}
+
+func TestRateLimitInterceptorTrustsForwardedIPOnlyFromGateway(t *testing.T) {
+
+	ipLimit, _ := ratelimit.ParseLimit("1/1m")
+	interceptor := RateLimitInterceptor(ratelimit.NewMemoryStore(time.Hour), ipLimit, ratelimit.Limit{}, nil)
+	req := &sharesecretgrpc.CreateSecretRequest{}
+
+	gateway := func(fwd string) metadata.MD {
+		return metadata.Join(metadata.Pairs("x-forwarded-for", fwd), GatewayMetadata())
+	}
+
+	// the gateway appends the IP of its client
+	assert.Nil(t, callRateLimited(interceptor, peerContext("127.0.0.1", gateway("1.1.1.1, 10.0.0.1")), req))
+	assert.Nil(t, callRateLimited(interceptor, peerContext("127.0.0.1", gateway("1.1.1.1, 10.0.0.2")), req))
+
+	err1 := callRateLimited(interceptor, peerContext("127.0.0.1", gateway("2.2.2.2, 10.0.0.1")), req)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err1))
+
+	// other local processes can not choose their IP
+	assert.Nil(t, callRateLimited(interceptor, peerContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "10.0.0.4")), req))
+
+	err2 := callRateLimited(interceptor, peerContext("127.0.0.1", metadata.Pairs("x-forwarded-for", "10.0.0.5", GatewayHeader, "guess")), req)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err2))
+
+	// nor other peers
+	assert.Nil(t, callRateLimited(interceptor, peerContext("10.0.0.3", metadata.Pairs("x-forwarded-for", "10.0.0.4")), req))
+
+	err3 := callRateLimited(interceptor, peerContext("10.0.0.3", metadata.Pairs("x-forwarded-for", "10.0.0.5")), req)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err3))
+}
+
+func TestRateLimitInterceptorSkipsTrustedProxies(t *testing.T) {
+
+	proxies, err := ParseTrustedProxies("10.1.0.0/16, 10.2.0.1")
+
+	assert.Nil(t, err)
+
+	ipLimit, _ := ratelimit.ParseLimit("1/1m")
+	interceptor := RateLimitInterceptor(ratelimit.NewMemoryStore(time.Hour), ipLimit, ratelimit.Limit{}, proxies)
+	req := &sharesecretgrpc.CreateSecretRequest{}
+
+	// the load balancer in front of the gateway appends the IP of the client, the first entry was sent by the client
+	gateway := metadata.Join(metadata.Pairs("x-forwarded-for", "3.3.3.3, 1.1.1.1, 10.1.0.5"), GatewayMetadata())
+
+	assert.Nil(t, callRateLimited(interceptor, peerContext("127.0.0.1", gateway), req))
+
+	// a trusted proxy calling gRPC directly
+	err1 := callRateLimited(interceptor, peerContext("10.2.0.1", metadata.Pairs("x-forwarded-for", "4.4.4.4, 1.1.1.1")), req)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err1))
+
+	// the load balancer itself is not limited with its clients
+	assert.Nil(t, callRateLimited(interceptor, peerContext("10.2.0.1", metadata.MD{}), req))
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+
+	proxies, err := ParseTrustedProxies("")
+
+	assert.Nil(t, err)
+	assert.Empty(t, proxies)
+
+	proxies, err = ParseTrustedProxies("10.0.0.1, 192.168.0.0/24,::1")
+
+	assert.Nil(t, err)
+	if assert.Len(t, proxies, 3) {
+		assert.True(t, proxies[0].Contains(net.ParseIP("10.0.0.1")))
+		assert.False(t, proxies[0].Contains(net.ParseIP("10.0.0.2")))
+		assert.True(t, proxies[1].Contains(net.ParseIP("192.168.0.200")))
+		assert.True(t, proxies[2].Contains(net.ParseIP("::1")))
+	}
+
+	_, err = ParseTrustedProxies("10.0.0.1,proxy")
+
+	assert.NotNil(t, err)
+
+	_, err = ParseTrustedProxies("10.0.0.0/33")
+
+	assert.NotNil(t, err)
+}
@@ -1,23 +1,40 @@
 package grpc
 
 import (
+	"context"
 	"fmt"
 	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
 	sharesecret "github.com/bernardosecades/sharesecret/internal"
 	"github.com/bernardosecades/sharesecret/internal/server"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/credentials"
 	"net"
-	"os"
 )
 
 type grpcServer struct {
-	config        server.Config
-	secretService sharesecret.SecretService
+	config server.Config
+	srv    *grpc.Server
 }
 
 func NewServer(config server.Config, ss sharesecret.SecretService) server.Server {
-	return &grpcServer{config: config, secretService: ss}
+
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(config.UnaryInterceptors...)}
+	if config.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config.TLS.ServerConfig())))
+	}
+
+	return &grpcServer{config: config, srv: NewGRPCServer(ss, opts...)}
+}
+
+// NewGRPCServer returns a gRPC server with the secret service registered
+func NewGRPCServer(ss sharesecret.SecretService, opts ...grpc.ServerOption) *grpc.Server {
+
+	srv := grpc.NewServer(opts...)
+
+	serviceServer := NewShareSecretServer(ss)
+	sharesecretgrpc.RegisterSecretServiceServer(srv, serviceServer)
+
+	return srv
 }
 
 func (s *grpcServer) Serve() error {
@@ -27,17 +44,28 @@ func (s *grpcServer) Serve() error {
 		return err
 	}
 
-	grpcLog := grpclog.NewLoggerV2(os.Stdout, os.Stderr, os.Stderr)
-	grpclog.SetLoggerV2(grpcLog)
-
-	srv := grpc.NewServer()
-
-	serviceServer := NewShareSecretServer(s.secretService)
-	sharesecretgrpc.RegisterSecretServiceServer(srv, serviceServer)
-
-	if err := srv.Serve(listener); err != nil {
+	if err := s.srv.Serve(listener); err != nil && err != grpc.ErrServerStopped {
 		return err
 	}
 
 	return nil
 }
+
+// Shutdown stops accepting connections and waits for the calls in flight (e.g. a SeeSecret that already
+// consumed the secret has to deliver its content), they are canceled if ctx is done before
+func (s *grpcServer) Shutdown(ctx context.Context) error {
+
+	stopped := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,162 @@
+// +build unit
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/server"
+	"github.com/bernardosecades/sharesecret/internal/server/servertest"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// slowSecretService blocks GetContentSecret until release is closed
+type slowSecretService struct {
+	sharesecret.SecretService
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s slowSecretService) GetContentSecret(ctx context.Context, id string, password string) (sharesecret.Secret, error) {
+	close(s.started)
+	<-s.release
+	return sharesecret.Secret{ID: id, Content: "this is my secret"}, nil
+}
+
+func freePort(t *testing.T) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to get a free port: %v", err)
+	}
+	defer l.Close()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+func TestShutdownWaitsForCallsInFlight(t *testing.T) {
+
+	port := freePort(t)
+	ss := slowSecretService{started: make(chan struct{}), release: make(chan struct{})}
+	srv := NewServer(server.Config{Protocol: "tcp", Host: "127.0.0.1", Port: port}, ss)
+
+	served := make(chan error)
+	go func() { served <- srv.Serve() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, "127.0.0.1:"+port, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	seen := make(chan *sharesecretgrpc.SeeSecretResponse)
+	go func() {
+		r, _ := sharesecretgrpc.NewSecretServiceClient(conn).SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd"})
+		seen <- r
+	}()
+	<-ss.started
+
+	shutdown := make(chan error)
+	go func() { shutdown <- srv.Shutdown(ctx) }()
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned with a call in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(ss.release)
+
+	r := <-seen
+	assert.NotNil(t, r)
+	assert.Equal(t, "this is my secret", r.GetContent())
+	assert.Nil(t, <-shutdown)
+	assert.Nil(t, <-served)
+}
+
+func TestShutdownCancelsCallsAfterTimeout(t *testing.T) {
+
+	port := freePort(t)
+	ss := slowSecretService{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(ss.release)
+	srv := NewServer(server.Config{Protocol: "tcp", Host: "127.0.0.1", Port: port}, ss)
+
+	served := make(chan error)
+	go func() { served <- srv.Serve() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, "127.0.0.1:"+port, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		_, _ = sharesecretgrpc.NewSecretServiceClient(conn).SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd"})
+	}()
+	<-ss.started
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shutdownCancel()
+
+	assert.Equal(t, context.DeadlineExceeded, srv.Shutdown(shutdownCtx))
+	assert.Nil(t, <-served)
+}
+
+func TestServeMutualTLS(t *testing.T) {
+
+	f := servertest.WriteCertificates(t, t.TempDir())
+	certs, err := server.NewCertificates(f.CertFile, f.KeyFile, f.CAFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+
+	port := freePort(t)
+	ss := slowSecretService{started: make(chan struct{}), release: make(chan struct{})}
+	close(ss.release)
+	srv := NewServer(server.Config{Protocol: "tcp", Host: "127.0.0.1", Port: port, TLS: certs}, ss)
+
+	served := make(chan error)
+	go func() { served <- srv.Serve() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	creds := credentials.NewTLS(servertest.ClientTLSConfig(t, f, true))
+	conn, err := grpc.DialContext(ctx, "localhost:"+port, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	r1, err1 := sharesecretgrpc.NewSecretServiceClient(conn).SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd"})
+
+	assert.Nil(t, err1)
+	assert.Equal(t, "this is my secret", r1.GetContent())
+
+	noCertCreds := credentials.NewTLS(servertest.ClientTLSConfig(t, f, false))
+	noCertConn, err := grpc.DialContext(ctx, "localhost:"+port, grpc.WithTransportCredentials(noCertCreds))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer noCertConn.Close()
+
+	_, err2 := sharesecretgrpc.NewSecretServiceClient(noCertConn).SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: "727d7040-aac7-4dc3-ab44-938bfba92ebd"})
+
+	assert.NotNil(t, err2)
+
+	assert.Nil(t, srv.Shutdown(ctx))
+	assert.Nil(t, <-served)
+}
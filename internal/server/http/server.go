@@ -2,31 +2,208 @@ package http
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
 
 	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
+	"github.com/bernardosecades/sharesecret/internal/server"
+	grpcserver "github.com/bernardosecades/sharesecret/internal/server/grpc"
 
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
-	httpAddr string
+	httpAddr     string
+	grpcEndpoint string
+	certs        *server.Certificates
+	metrics      http.Handler
+	srv          *http.Server
 }
 
-func NewServer(httpAddr string) *Server {
-	return &Server{httpAddr: httpAddr}
+// NewServer returns the REST gateway listening on httpAddr, it forwards the requests to the gRPC server at grpcEndpoint.
+// Both of them serve with certs when it is not nil. metrics serves /metrics when it is not nil.
+func NewServer(httpAddr string, grpcEndpoint string, certs *server.Certificates, metrics http.Handler) *Server {
+
+	srv := &http.Server{Addr: httpAddr}
+	if certs != nil {
+		srv.TLSConfig = certs.ServerConfig()
+	}
+
+	return &Server{httpAddr: httpAddr, grpcEndpoint: grpcEndpoint, certs: certs, metrics: metrics, srv: srv}
 }
 
+// Serve serves the REST gateway until the server is shut down
 func (s *Server) Serve(ctx context.Context) error {
 
-	mux := runtime.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithReturnConnectionError()}
-
-	err := sharesecretgrpc.RegisterSecretServiceHandlerFromEndpoint(ctx, mux, s.httpAddr, opts)
+	// fail on start when the gRPC server is not reachable
+	mux, err := NewGatewayHandler(ctx, s.grpcEndpoint, s.certs, grpc.WithReturnConnectionError())
 	if err != nil {
 		return err
 	}
 
-	return http.ListenAndServe(s.httpAddr, mux)
+	s.srv.Handler = WithMetrics(mux, s.metrics)
+	if s.certs != nil {
+		// the certificates come from TLSConfig
+		err = s.srv.ListenAndServeTLS("", "")
+	} else {
+		err = s.srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// NewGatewayHandler returns the handler of the REST API that forwards the requests to the gRPC server at grpcEndpoint,
+// the connection to the gRPC server is closed when ctx is done. The gRPC server is dialed with TLS when certs (the ones
+// of the gRPC server) is not nil. Every request is traced with a span, the trace context (W3C traceparent from the
+// HTTP headers or a new one) is sent to the gRPC server as metadata.
+func NewGatewayHandler(ctx context.Context, grpcEndpoint string, certs *server.Certificates, opts ...grpc.DialOption) (http.Handler, error) {
+
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithMetadata(func(context.Context, *http.Request) metadata.MD {
+			return grpcserver.GatewayMetadata()
+		}),
+	)
+	if certs != nil {
+		opts = append([]grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(certs.LoopbackConfig()))}, opts...)
+	} else {
+		opts = append([]grpc.DialOption{grpc.WithInsecure()}, opts...)
+	}
+
+	opts = append(opts, grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()))
+
+	err := sharesecretgrpc.RegisterSecretServiceHandlerFromEndpoint(ctx, mux, grpcEndpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	traced := otelhttp.NewHandler(mux, "gateway", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.RequestURI
+	}))
+
+	return withoutCredentialsInQuery(mux, withRouteTarget(traced)), nil
+}
+
+// routes are the paths of the REST API (proto/secret.proto), a segment in braces is a parameter
+var routes = []string{"/v1/secret", "/v1/secret/{id}", "/v1/secret/{id}/info"}
+
+// route returns the route of path, unknown paths are "unknown" because they can have anything
+func route(path string) string {
+
+	segments := strings.Split(path, "/")
+	for _, r := range routes {
+		if matchRoute(strings.Split(r, "/"), segments) {
+			return r
+		}
+	}
+
+	return "unknown"
+}
+
+func matchRoute(route []string, segments []string) bool {
+
+	if len(route) != len(segments) {
+		return false
+	}
+
+	for i := range route {
+		if strings.HasPrefix(route[i], "{") {
+			if len(segments[i]) == 0 {
+				return false
+			}
+		} else if route[i] != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// withRouteTarget serves next with the route of the request as request URI, the span of the gateway takes its
+// name and http.target from it so the spans have neither the secret IDs of the paths nor the query. The gateway
+// routes with URL, it does not read the request URI.
+func withRouteTarget(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(r.Context())
+		r.RequestURI = route(r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// credentialsInQuery are the fields of the requests that the gateway would take from the query (by their proto and
+// JSON names), they are rejected because the URLs end in access logs, proxies and traces. They are sent as headers
+// (Grpc-Metadata-...).
+var credentialsInQuery = []string{"password", "deletion_token", "deletionToken"}
+
+// withoutCredentialsInQuery returns InvalidArgument (400) with the error handler of mux for the requests with
+// credentials in the query, the rest are served by next
+func withoutCredentialsInQuery(mux *runtime.ServeMux, next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		query := r.URL.Query()
+		for _, key := range credentialsInQuery {
+			if _, ok := query[key]; ok {
+				st, _ := status.New(codes.InvalidArgument, fmt.Sprintf("%s must be sent as header, not in the URL", key)).
+					WithDetails(&errdetails.ErrorInfo{Reason: grpcserver.ReasonCredentialsInURL, Domain: grpcserver.ErrorDomain})
+				_, outbound := runtime.MarshalerForRequest(mux, r)
+				runtime.HTTPError(r.Context(), mux, outbound, w, r, st.Err())
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithMetrics returns a handler serving /metrics with metrics and the rest with gateway, gateway if metrics is nil
+func WithMetrics(gateway http.Handler, metrics http.Handler) http.Handler {
+
+	if metrics == nil {
+		return gateway
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	mux.Handle("/", gateway)
+
+	return mux
+}
+
+// headerMatcher forwards the API keys as metadata, Authorization is always forwarded
+func headerMatcher(key string) (string, bool) {
+
+	if strings.EqualFold(key, "X-Api-Key") {
+		return "x-api-key", true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher returns the seconds to wait of the rate limited calls as Retry-After
+func outgoingHeaderMatcher(key string) (string, bool) {
+
+	if key == "retry-after" {
+		return "Retry-After", true
+	}
+
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
+
+// Shutdown stops accepting connections and waits for the requests in flight until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
 }
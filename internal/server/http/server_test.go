@@ -0,0 +1,208 @@
+// +build e2e
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bernardosecades/sharesecret/cmd"
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/server"
+	grpcserver "github.com/bernardosecades/sharesecret/internal/server/grpc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var baseURL string
+
+func init() {
+
+	secretKey := os.Getenv("SECRET_KEY")
+	secretPassword := os.Getenv("SECRET_PASSWORD")
+
+	secretRepository, err := cmd.NewSecretRepositoryFromEnv(context.Background())
+	if err != nil {
+		log.Fatalf("Error to load storage: %v", err)
+	}
+	secretService := sharesecret.NewSecretService(secretRepository, secretKey, secretPassword)
+
+	grpcPort := freePort()
+	grpcSrv := grpcserver.NewServer(server.Config{Protocol: "tcp", Host: "127.0.0.1", Port: grpcPort}, secretService)
+	go func() {
+		if err := grpcSrv.Serve(); err != nil {
+			log.Fatalf("gRPC server exited with error: %v", err)
+		}
+	}()
+
+	httpAddr := net.JoinHostPort("127.0.0.1", freePort())
+	httpSrv := NewServer(httpAddr, net.JoinHostPort("127.0.0.1", grpcPort), nil, nil)
+	go func() {
+		if err := httpSrv.Serve(context.Background()); err != nil {
+			log.Fatalf("HTTP server exited with error: %v", err)
+		}
+	}()
+
+	baseURL = "http://" + httpAddr
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", httpAddr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	log.Fatalf("HTTP server is not listening at %s", httpAddr)
+}
+
+func freePort() string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalf("Failed to get a free port: %v", err)
+	}
+	defer l.Close()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+func doJSON(t *testing.T, method string, path string, body string, headers map[string]string) (int, map[string]interface{}) {
+
+	req, err := http.NewRequest(method, baseURL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var r map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatalf("Failed to decode response of %s %s: %v", method, path, err)
+	}
+
+	return resp.StatusCode, r
+}
+
+// reason returns the reason of the error details in the response r
+func reason(r map[string]interface{}) string {
+
+	details, _ := r["details"].([]interface{})
+	for _, d := range details {
+		if info, ok := d.(map[string]interface{}); ok && info["@type"] == "type.googleapis.com/google.rpc.ErrorInfo" {
+			reason, _ := info["reason"].(string)
+			return reason
+		}
+	}
+
+	return ""
+}
+
+func TestRESTValidationError(t *testing.T) {
+
+	code, r := doJSON(t, http.MethodPost, "/v1/secret", `{"content": ""}`, nil)
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "EMPTY_CONTENT", reason(r))
+}
+
+func TestRESTCreateAndSeeSecret(t *testing.T) {
+
+	code1, r1 := doJSON(t, http.MethodPost, "/v1/secret", `{"content": "This is my secret by REST"}`, nil)
+
+	assert.Equal(t, http.StatusOK, code1)
+	id, _ := r1["id"].(string)
+	assert.Len(t, id, 36)
+	assert.NotEmpty(t, r1["deletion_token"])
+
+	code2, r2 := doJSON(t, http.MethodGet, "/v1/secret/"+id+"/info", "", nil)
+
+	assert.Equal(t, http.StatusOK, code2)
+	assert.Equal(t, true, r2["exists"])
+
+	code3, r3 := doJSON(t, http.MethodGet, "/v1/secret/"+id, "", nil)
+
+	assert.Equal(t, http.StatusOK, code3)
+	assert.Equal(t, "This is my secret by REST", r3["content"])
+
+	code4, r4 := doJSON(t, http.MethodGet, "/v1/secret/"+id, "", nil)
+
+	assert.Equal(t, http.StatusNotFound, code4)
+	assert.Equal(t, "SECRET_NOT_FOUND", reason(r4))
+}
+
+func TestRESTSeeSecretWithPasswordHeader(t *testing.T) {
+
+	_, r1 := doJSON(t, http.MethodPost, "/v1/secret", `{"content": "This is my secret by REST with password", "password": "1234"}`, nil)
+	id, _ := r1["id"].(string)
+
+	code2, r2 := doJSON(t, http.MethodGet, "/v1/secret/"+id, "", nil)
+
+	assert.Equal(t, http.StatusUnauthorized, code2)
+	assert.Equal(t, "PASSWORD_REQUIRED", reason(r2))
+
+	code3, r3 := doJSON(t, http.MethodGet, "/v1/secret/"+id, "", map[string]string{"Grpc-Metadata-Password": "1234"})
+
+	assert.Equal(t, http.StatusOK, code3)
+	assert.Equal(t, "This is my secret by REST with password", r3["content"])
+}
+
+func TestRESTCredentialsInURL(t *testing.T) {
+
+	_, r1 := doJSON(t, http.MethodPost, "/v1/secret", `{"content": "This is my secret by REST with credentials in URL", "password": "1234"}`, nil)
+	id, _ := r1["id"].(string)
+	token, _ := r1["deletion_token"].(string)
+
+	code2, r2 := doJSON(t, http.MethodGet, "/v1/secret/"+id+"?password=1234", "", nil)
+
+	assert.Equal(t, http.StatusBadRequest, code2)
+	assert.Equal(t, "CREDENTIALS_IN_URL", reason(r2))
+
+	code3, r3 := doJSON(t, http.MethodDelete, "/v1/secret/"+id+"?deletion_token="+token, "", nil)
+
+	assert.Equal(t, http.StatusBadRequest, code3)
+	assert.Equal(t, "CREDENTIALS_IN_URL", reason(r3))
+
+	// the gateway takes the fields by their JSON names too
+	code4, r4 := doJSON(t, http.MethodDelete, "/v1/secret/"+id+"?deletionToken="+token, "", nil)
+
+	assert.Equal(t, http.StatusBadRequest, code4)
+	assert.Equal(t, "CREDENTIALS_IN_URL", reason(r4))
+
+	_, r5 := doJSON(t, http.MethodGet, "/v1/secret/"+id+"/info", "", nil)
+
+	assert.Equal(t, true, r5["exists"])
+}
+
+func TestRESTDeleteSecret(t *testing.T) {
+
+	_, r1 := doJSON(t, http.MethodPost, "/v1/secret", `{"content": "This is my secret by REST to delete"}`, nil)
+	id, _ := r1["id"].(string)
+	token, _ := r1["deletion_token"].(string)
+
+	code2, r2 := doJSON(t, http.MethodDelete, "/v1/secret/"+id, "", map[string]string{"Grpc-Metadata-Deletion-Token": "wrong"})
+
+	assert.Equal(t, http.StatusForbidden, code2)
+	assert.Equal(t, "INVALID_DELETION_TOKEN", reason(r2))
+
+	code3, _ := doJSON(t, http.MethodDelete, "/v1/secret/"+id, "", map[string]string{"Grpc-Metadata-Deletion-Token": token})
+
+	assert.Equal(t, http.StatusOK, code3)
+
+	_, r4 := doJSON(t, http.MethodGet, "/v1/secret/"+id+"/info", "", nil)
+
+	assert.NotEqual(t, true, r4["exists"])
+}
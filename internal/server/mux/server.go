@@ -0,0 +1,189 @@
+// Package mux serves gRPC, gRPC-Web and REST on a single listener, the connections are dispatched by content type.
+package mux
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/server"
+	sharesecretgrpc "github.com/bernardosecades/sharesecret/internal/server/grpc"
+	sharesecrethttp "github.com/bernardosecades/sharesecret/internal/server/http"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"github.com/soheilhy/cmux"
+	"google.golang.org/grpc"
+)
+
+type muxServer struct {
+	config  server.Config
+	grpcSrv *grpc.Server
+	webSrv  *grpcweb.WrappedGrpcServer
+	httpSrv *http.Server
+
+	// the gateway connection to the gRPC server is closed on shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	listener net.Listener
+	closing  bool
+}
+
+// NewServer returns a server dispatching the native gRPC calls to the gRPC server, the application/grpc-web
+// requests to the gRPC-Web wrapper and the rest of requests to the REST gateway, all of them on config.Port
+func NewServer(config server.Config, ss sharesecret.SecretService) server.Server {
+
+	grpcSrv := sharesecretgrpc.NewGRPCServer(ss, grpc.ChainUnaryInterceptor(config.UnaryInterceptors...))
+	webSrv := grpcweb.WrapServer(grpcSrv, grpcweb.WithOriginFunc(allowedOrigin(config.AllowedOrigins)))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &muxServer{
+		config:  config,
+		grpcSrv: grpcSrv,
+		webSrv:  webSrv,
+		httpSrv: &http.Server{},
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (s *muxServer) Serve() error {
+
+	listener, err := net.Listen(s.config.Protocol, net.JoinHostPort(s.config.Host, s.config.Port))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		listener.Close()
+		return nil
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	// the gateway dials this same listener so REST requests are handled as gRPC calls, the dial does not block
+	// because nothing is served yet
+	gateway, err := sharesecrethttp.NewGatewayHandler(s.ctx, s.config.Endpoint(), s.config.TLS)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	s.httpSrv.Handler = s.handler(sharesecrethttp.WithMetrics(gateway, s.config.MetricsHandler))
+
+	if s.config.TLS != nil {
+		return s.serveTLS(listener)
+	}
+
+	m := cmux.New(listener)
+	// gRPC clients send the settings frame first and wait for the server one before sending the headers
+	grpcListener := m.MatchWithWriters(
+		cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"),
+		cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc+proto"),
+	)
+	httpListener := m.Match(cmux.Any())
+
+	errs := make(chan error, 3)
+	go func() { errs <- s.grpcSrv.Serve(grpcListener) }()
+	go func() { errs <- s.httpSrv.Serve(httpListener) }()
+	go func() { errs <- m.Serve() }()
+
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil && err != http.ErrServerClosed && !s.isClosing() {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serveTLS serves every protocol with the HTTP server because the content type of an HTTP/2 connection can only
+// be read after the TLS handshake, the native gRPC calls are handled by the gRPC server as HTTP requests
+func (s *muxServer) serveTLS(listener net.Listener) error {
+
+	s.httpSrv.TLSConfig = s.config.TLS.ServerConfig()
+
+	// the certificates come from TLSConfig
+	if err := s.httpSrv.ServeTLS(listener, "", ""); err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+func (s *muxServer) handler(gateway http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case s.webSrv.IsGrpcWebRequest(r) || s.webSrv.IsAcceptableGrpcCorsRequest(r):
+			s.webSrv.ServeHTTP(w, r)
+		case r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc"):
+			s.grpcSrv.ServeHTTP(w, r)
+		default:
+			gateway.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Shutdown stops accepting connections and waits for the requests in flight until ctx is done, the HTTP ones
+// first because REST requests are forwarded to the gRPC server
+func (s *muxServer) Shutdown(ctx context.Context) error {
+
+	s.mu.Lock()
+	s.closing = true
+	listener := s.listener
+	s.mu.Unlock()
+
+	defer s.cancel()
+
+	// the gRPC server can't wait for the calls it handles as HTTP requests (gRPC-Web and native gRPC with TLS),
+	// it is stopped right away if the HTTP server could not wait for them
+	if err := s.httpSrv.Shutdown(ctx); err != nil {
+		s.grpcSrv.Stop()
+		return err
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcSrv.GracefulStop()
+		close(stopped)
+	}()
+
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcSrv.Stop()
+		err = ctx.Err()
+	}
+
+	if listener != nil {
+		listener.Close()
+	}
+
+	return err
+}
+
+func (s *muxServer) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+func allowedOrigin(origins []string) func(origin string) bool {
+	return func(origin string) bool {
+		if len(origins) == 0 {
+			return true
+		}
+		for _, o := range origins {
+			if strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,366 @@
+// +build unit
+
+package mux
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sharesecretgrpc "github.com/bernardosecades/sharesecret/genproto"
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/auth"
+	"github.com/bernardosecades/sharesecret/internal/ratelimit"
+	"github.com/bernardosecades/sharesecret/internal/server"
+	grpcserver "github.com/bernardosecades/sharesecret/internal/server/grpc"
+	"github.com/bernardosecades/sharesecret/internal/server/servertest"
+	"github.com/bernardosecades/sharesecret/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+)
+
+func freePort(t *testing.T) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to get a free port: %v", err)
+	}
+	defer l.Close()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+// serve starts a server on a free port (with TLS if certs is not nil) and returns its address, the server is
+// shut down at the end of the test
+func serve(t *testing.T, certs *server.Certificates, interceptors ...grpc.UnaryServerInterceptor) string {
+	return serveConfig(t, server.Config{TLS: certs, UnaryInterceptors: interceptors})
+}
+
+// serveConfig is serve with the rest of the config
+func serveConfig(t *testing.T, config server.Config) string {
+
+	ss := sharesecret.NewSecretService(memory.NewMemorySecretRepository(), "11111111111111111111111111111111", "@myPassword")
+	config.Protocol, config.Host, config.Port = "tcp", "127.0.0.1", freePort(t)
+	srv := NewServer(config, ss)
+
+	served := make(chan error)
+	go func() { served <- srv.Serve() }()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.Nil(t, srv.Shutdown(ctx))
+		assert.Nil(t, <-served)
+	})
+
+	addr := config.Endpoint()
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return addr
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Server is not listening at %s", addr)
+
+	return ""
+}
+
+func TestServeGRPC(t *testing.T) {
+	testServeGRPC(t, serve(t, nil), grpc.WithInsecure())
+}
+
+func TestServeGRPCWeb(t *testing.T) {
+	testServeGRPCWeb(t, "http://"+serve(t, nil), http.DefaultClient)
+}
+
+func TestServeREST(t *testing.T) {
+	testServeREST(t, "http://"+serve(t, nil), http.DefaultClient)
+}
+
+func TestServeMutualTLS(t *testing.T) {
+
+	f := servertest.WriteCertificates(t, t.TempDir())
+	certs, err := server.NewCertificates(f.CertFile, f.KeyFile, f.CAFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+
+	addr := serve(t, certs)
+	tlsConfig := servertest.ClientTLSConfig(t, f, true)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true}}
+
+	t.Run("GRPC", func(t *testing.T) {
+		testServeGRPC(t, addr, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	})
+	t.Run("GRPCWeb", func(t *testing.T) {
+		testServeGRPCWeb(t, "https://"+addr, client)
+	})
+	t.Run("REST", func(t *testing.T) {
+		testServeREST(t, "https://"+addr, client)
+	})
+	t.Run("WithoutClientCertificate", func(t *testing.T) {
+		noCertClient := &http.Client{Transport: &http.Transport{TLSClientConfig: servertest.ClientTLSConfig(t, f, false)}}
+		_, err := noCertClient.Get("https://" + addr + "/v1/secret/727d7040-aac7-4dc3-ab44-938bfba92ebd")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestServeAuthenticatedREST(t *testing.T) {
+
+	keys := auth.NewAPIKeyFile(filepath.Join(t.TempDir(), "api_keys.json"))
+	key, _ := keys.Create("frontend")
+	baseURL := "http://" + serve(t, nil, grpcserver.AuthInterceptor(keys, grpcserver.CreateSecretMethod))
+
+	create := func(headers map[string]string) (int, string) {
+		req, _ := http.NewRequest(http.MethodPost, baseURL+"/v1/secret", strings.NewReader(`{"content": "This is my secret by REST"}`))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Create secret failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var r map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&r)
+		id, _ := r["id"].(string)
+
+		return resp.StatusCode, id
+	}
+
+	code1, _ := create(nil)
+
+	assert.Equal(t, http.StatusUnauthorized, code1)
+
+	code2, _ := create(map[string]string{"X-Api-Key": "ssk_unknown"})
+
+	assert.Equal(t, http.StatusUnauthorized, code2)
+
+	code3, _ := create(map[string]string{"X-Api-Key": key})
+
+	assert.Equal(t, http.StatusOK, code3)
+
+	code4, id := create(map[string]string{"Authorization": "Bearer " + key})
+
+	assert.Equal(t, http.StatusOK, code4)
+
+	// the link recipients see the secret without credentials
+	resp, err := http.Get(baseURL + "/v1/secret/" + id)
+	if err != nil {
+		t.Fatalf("See secret failed: %v", err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServeRateLimitedREST(t *testing.T) {
+
+	secretLimit, _ := ratelimit.ParseLimit("2/1m")
+	baseURL := "http://" + serve(t, nil, grpcserver.RateLimitInterceptor(ratelimit.NewMemoryStore(time.Hour), ratelimit.Limit{}, secretLimit, nil))
+
+	see := func() *http.Response {
+		resp, err := http.Get(baseURL + "/v1/secret/2a2fb1d7-34bd-4b5e-9c17-1e5a1c4d6f0e")
+		if err != nil {
+			t.Fatalf("See secret failed: %v", err)
+		}
+		resp.Body.Close()
+
+		return resp
+	}
+
+	assert.Equal(t, http.StatusNotFound, see().StatusCode)
+	assert.Equal(t, http.StatusNotFound, see().StatusCode)
+
+	resp := see()
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+}
+
+func TestServeMetrics(t *testing.T) {
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("sharesecret_secrets 1\n"))
+	})
+	baseURL := "http://" + serveConfig(t, server.Config{MetricsHandler: metrics})
+
+	resp, err := http.Get(baseURL + "/metrics")
+	if err != nil {
+		t.Fatalf("Get metrics failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	b, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "sharesecret_secrets 1\n", string(b))
+
+	// the rest is still served by the gateway
+	testServeREST(t, baseURL, http.DefaultClient)
+}
+
+func TestServeTracedREST(t *testing.T) {
+
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	baseURL := "http://" + serve(t, nil, otelgrpc.UnaryServerInterceptor())
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	id := "2a2fb1d7-34bd-4b5e-9c17-1e5a1c4d6f0e"
+	req, _ := http.NewRequest(http.MethodGet, baseURL+"/v1/secret/"+id+"/info?lang=en", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Secret info failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// the span of the gateway ends after the response is sent
+	assert.Eventually(t, func() bool { return len(sr.Ended()) == 3 }, time.Second, 10*time.Millisecond)
+
+	var names []string
+	for _, s := range sr.Ended() {
+		assert.Equal(t, traceID, s.SpanContext().TraceID().String())
+		names = append(names, s.Name())
+
+		// the ID of the secret gives access to it
+		for _, a := range s.Attributes() {
+			assert.NotContains(t, a.Value.Emit(), id)
+			assert.NotContains(t, a.Value.Emit(), "lang=en")
+		}
+		if s.Name() == "GET /v1/secret/{id}/info" {
+			assert.Contains(t, s.Attributes(), semconv.HTTPTargetKey.String("/v1/secret/{id}/info"))
+		}
+	}
+
+	// the gRPC call of the gateway (client) and its handler (server) in the span of the REST request
+	assert.ElementsMatch(t, []string{"GET /v1/secret/{id}/info", "sharesecret.SecretService/GetSecretInfo", "sharesecret.SecretService/GetSecretInfo"}, names)
+}
+
+func testServeGRPC(t *testing.T, addr string, creds grpc.DialOption) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr, creds, grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	client := sharesecretgrpc.NewSecretServiceClient(conn)
+
+	r1, err1 := client.CreateSecret(ctx, &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret by gRPC"})
+
+	assert.Nil(t, err1)
+	assert.Len(t, r1.GetId(), 36)
+
+	r2, err2 := client.SeeSecret(ctx, &sharesecretgrpc.SeeSecretRequest{Id: r1.GetId()})
+
+	assert.Nil(t, err2)
+	assert.Equal(t, "This is my secret by gRPC", r2.GetContent())
+}
+
+func testServeGRPCWeb(t *testing.T, baseURL string, client *http.Client) {
+
+	r1 := &sharesecretgrpc.CreateSecretResponse{}
+	grpcWeb(t, baseURL, client, "CreateSecret", &sharesecretgrpc.CreateSecretRequest{Content: "This is my secret by gRPC-Web"}, r1)
+
+	assert.Len(t, r1.GetId(), 36)
+
+	r2 := &sharesecretgrpc.SeeSecretResponse{}
+	grpcWeb(t, baseURL, client, "SeeSecret", &sharesecretgrpc.SeeSecretRequest{Id: r1.GetId()}, r2)
+
+	assert.Equal(t, "This is my secret by gRPC-Web", r2.GetContent())
+}
+
+func testServeREST(t *testing.T, baseURL string, client *http.Client) {
+
+	resp1, err1 := client.Post(baseURL+"/v1/secret", "application/json", strings.NewReader(`{"content": "This is my secret by REST"}`))
+	if err1 != nil {
+		t.Fatalf("Create secret failed: %v", err1)
+	}
+	defer resp1.Body.Close()
+
+	var r1 map[string]interface{}
+	assert.Nil(t, json.NewDecoder(resp1.Body).Decode(&r1))
+	assert.Equal(t, http.StatusOK, resp1.StatusCode)
+	id, _ := r1["id"].(string)
+	assert.Len(t, id, 36)
+
+	resp2, err2 := client.Get(baseURL + "/v1/secret/" + id)
+	if err2 != nil {
+		t.Fatalf("See secret failed: %v", err2)
+	}
+	defer resp2.Body.Close()
+
+	var r2 map[string]interface{}
+	assert.Nil(t, json.NewDecoder(resp2.Body).Decode(&r2))
+	assert.Equal(t, http.StatusOK, resp2.StatusCode)
+	assert.Equal(t, "This is my secret by REST", r2["content"])
+}
+
+// grpcWeb calls method as a browser does, each message is framed as flag (0 for data) || length || message
+func grpcWeb(t *testing.T, baseURL string, client *http.Client, method string, in proto.Message, out proto.Message) {
+
+	msg, err := proto.Marshal(in)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	body := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(msg)))
+	body = append(body, msg...)
+
+	req, _ := http.NewRequest(http.MethodPost, baseURL+"/sharesecret.SecretService/"+method, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("X-Grpc-Web", "1")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+	if len(raw) < 5 || raw[0] != 0 || len(raw) < 5+int(binary.BigEndian.Uint32(raw[1:5])) {
+		t.Fatalf("%s returned no message (grpc-status %q)", method, resp.Header.Get("Grpc-Status"))
+	}
+
+	if err := proto.Unmarshal(raw[5:5+binary.BigEndian.Uint32(raw[1:5])], out); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+}
@@ -1,13 +1,46 @@
 package server
 
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+)
+
 // Server define a server behaviour
 type Server interface {
-	// Serve serves a service's server implementation
+	// Serve serves a service's server implementation, it returns nil when the server is shut down
 	Serve() error
+	// Shutdown stops the server waiting for the requests in flight until ctx is done
+	Shutdown(ctx context.Context) error
 }
 
 type Config struct {
 	Protocol string
 	Host     string
 	Port     string
+
+	// TLS serves with the certificates, without TLS when it is nil
+	TLS *Certificates
+
+	// UnaryInterceptors are chained in order around every call, REST and gRPC-Web requests included
+	UnaryInterceptors []grpc.UnaryServerInterceptor
+
+	// AllowedOrigins are the origins of the browsers allowed to call with gRPC-Web, all if empty
+	AllowedOrigins []string
+
+	// MetricsHandler serves /metrics on the HTTP server, there is no such endpoint when it is nil
+	MetricsHandler http.Handler
+}
+
+// Endpoint returns the address to dial the server, a server listening on all interfaces is dialed on localhost
+func (c Config) Endpoint() string {
+
+	host := c.Host
+	if ip := net.ParseIP(host); len(host) == 0 || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+
+	return net.JoinHostPort(host, c.Port)
 }
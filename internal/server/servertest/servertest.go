@@ -0,0 +1,151 @@
+// Package servertest implements helpers for the tests of the servers.
+package servertest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Certificates are the files written by WriteCertificates
+type Certificates struct {
+	CAFile         string
+	CertFile       string
+	KeyFile        string
+	ClientCertFile string
+	ClientKeyFile  string
+	// ServerOnlyCertFile is a server certificate valid only for server authentication
+	ServerOnlyCertFile string
+	ServerOnlyKeyFile  string
+}
+
+// WriteCertificates writes to dir a CA, a server certificate (for localhost and 127.0.0.1, valid for client
+// authentication as well), a server certificate valid only for server authentication and a client certificate, all
+// issued by the CA
+func WriteCertificates(t *testing.T, dir string) Certificates {
+
+	caKey := newKey(t)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sharesecret test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	c := Certificates{
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		CertFile:       filepath.Join(dir, "server.pem"),
+		KeyFile:        filepath.Join(dir, "server.key"),
+		ClientCertFile: filepath.Join(dir, "client.pem"),
+		ClientKeyFile:  filepath.Join(dir, "client.key"),
+
+		ServerOnlyCertFile: filepath.Join(dir, "server-only.pem"),
+		ServerOnlyKeyFile:  filepath.Join(dir, "server-only.key"),
+	}
+
+	writePEM(t, c.CAFile, "CERTIFICATE", caDER)
+
+	server := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	writeCertificate(t, server, ca, caKey, c.CertFile, c.KeyFile)
+
+	serverOnly := &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	writeCertificate(t, serverOnly, ca, caKey, c.ServerOnlyCertFile, c.ServerOnlyKeyFile)
+
+	client := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "sharesecret test client"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	writeCertificate(t, client, ca, caKey, c.ClientCertFile, c.ClientKeyFile)
+
+	return c
+}
+
+// ClientTLSConfig returns the TLS configuration of a client trusting the CA, with the client certificate if withCert
+func ClientTLSConfig(t *testing.T, c Certificates, withCert bool) *tls.Config {
+
+	pem, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		t.Fatalf("Failed to read CA certificate: %v", err)
+	}
+
+	config := &tls.Config{RootCAs: x509.NewCertPool()}
+	config.RootCAs.AppendCertsFromPEM(pem)
+
+	if withCert {
+		cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			t.Fatalf("Failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config
+}
+
+func writeCertificate(t *testing.T, template *x509.Certificate, ca *x509.Certificate, caKey *ecdsa.PrivateKey, certFile string, keyFile string) {
+
+	key := newKey(t)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	return key
+}
+
+func writePEM(t *testing.T, file string, blockType string, der []byte) {
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", file, err)
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// All errors reported by the certificates
+var (
+	ErrInvalidClientCA       = errors.New("client CA file has no PEM certificate")
+	ErrUnexpectedCertificate = errors.New("peer certificate is not the server certificate")
+	ErrGatewayCertificate    = errors.New("server certificate is not valid for client authentication, set a gateway certificate")
+)
+
+// CertificatesOption configures the certificates
+type CertificatesOption func(*Certificates)
+
+// WithGatewayCertificate sets the client certificate presented by the REST gateway with mutual TLS, the server
+// certificate is presented when it is not set
+func WithGatewayCertificate(certFile string, keyFile string) CertificatesOption {
+	return func(c *Certificates) {
+		c.gatewayCertFile = certFile
+		c.gatewayKeyFile = keyFile
+	}
+}
+
+// Certificates holds the server certificate and, for mutual TLS, the CA verifying the client certificates.
+// The files are read again when they change so a renewed certificate is served without a restart.
+type Certificates struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	gatewayCertFile string
+	gatewayKeyFile  string
+
+	mu          sync.Mutex
+	modTimes    []time.Time
+	cert        *tls.Certificate
+	clientCAs   *x509.CertPool
+	gatewayCert *tls.Certificate
+}
+
+// NewCertificates loads the certificate and key files, clients have to present a certificate issued by
+// clientCAFile when it is not empty
+func NewCertificates(certFile string, keyFile string, clientCAFile string, opts ...CertificatesOption) (*Certificates, error) {
+
+	c := &Certificates{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if err := c.load(c.stat()); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ServerConfig returns the TLS configuration of a server, every handshake uses the current files
+func (c *Certificates) ServerConfig() *tls.Config {
+
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	config := base.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cert, clientCAs := c.current()
+
+		cfg := base.Clone()
+		cfg.Certificates = []tls.Certificate{*cert}
+		if clientCAs != nil {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			cfg.ClientCAs = clientCAs
+		}
+
+		return cfg, nil
+	}
+	// http.Server requires a certificate source on the top level configuration
+	config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, _ := c.current()
+		return cert, nil
+	}
+
+	return config
+}
+
+// LoopbackConfig returns the TLS configuration of a client dialing the server itself (e.g. the REST gateway),
+// only the server certificate is trusted and the gateway certificate (the server one if it is not set) is presented
+// for mutual TLS
+func (c *Certificates) LoopbackConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the chain and host name are not verified, VerifyPeerCertificate pins the server certificate
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			cert, _ := c.current()
+			if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], cert.Certificate[0]) {
+				return ErrUnexpectedCertificate
+			}
+			return nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := c.current()
+
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			if c.gatewayCert != nil {
+				return c.gatewayCert, nil
+			}
+			return cert, nil
+		},
+	}
+}
+
+// current returns the certificates, they are loaded again when a file changed. A file being replaced can be
+// invalid for a while (e.g. the certificate is written before the key), the previous certificates are kept
+// until the next change.
+func (c *Certificates) current() (*tls.Certificate, *x509.CertPool) {
+
+	modTimes := c.stat()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !equalTimes(modTimes, c.modTimes) {
+		if err := c.load(modTimes); err != nil {
+			log.Printf("Error to reload certificates, keeping the previous ones: %v\n", err)
+			c.modTimes = modTimes
+		}
+	}
+
+	return c.cert, c.clientCAs
+}
+
+// load must be called with c.mu held once c is shared
+func (c *Certificates) load(modTimes []time.Time) error {
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if len(c.clientCAFile) > 0 {
+		pem, err := ioutil.ReadFile(c.clientCAFile)
+		if err != nil {
+			return err
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return ErrInvalidClientCA
+		}
+	}
+
+	var gatewayCert *tls.Certificate
+	if len(c.gatewayCertFile) > 0 || len(c.gatewayKeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(c.gatewayCertFile, c.gatewayKeyFile)
+		if err != nil {
+			return err
+		}
+		gatewayCert = &cert
+	} else if clientCAs != nil {
+		// the gateway would present the server certificate, the server rejects it without client authentication
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return err
+		}
+		if !clientAuth(leaf) {
+			return ErrGatewayCertificate
+		}
+	}
+
+	c.cert, c.clientCAs, c.gatewayCert, c.modTimes = &cert, clientCAs, gatewayCert, modTimes
+
+	return nil
+}
+
+func (c *Certificates) stat() []time.Time {
+
+	var modTimes []time.Time
+	for _, f := range []string{c.certFile, c.keyFile, c.clientCAFile, c.gatewayCertFile, c.gatewayKeyFile} {
+		var t time.Time
+		if len(f) > 0 {
+			if fi, err := os.Stat(f); err == nil {
+				t = fi.ModTime()
+			}
+		}
+		modTimes = append(modTimes, t)
+	}
+
+	return modTimes
+}
+
+// clientAuth reports whether cert can be used for client authentication, a certificate without extended key usage
+// can be used for anything
+func clientAuth(cert *x509.Certificate) bool {
+
+	if len(cert.ExtKeyUsage) == 0 {
+		return true
+	}
+
+	for _, u := range cert.ExtKeyUsage {
+		if u == x509.ExtKeyUsageClientAuth || u == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equalTimes(a []time.Time, b []time.Time) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,225 @@
+// +build unit
+
+package server
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bernardosecades/sharesecret/internal/server/servertest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// handshake returns the certificate served with serverConfig to a client with clientConfig
+func handshake(serverConfig *tls.Config, clientConfig *tls.Config) ([]byte, error) {
+
+	// a TCP connection rather than net.Pipe, the peers write at the same time when a handshake fails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	c2, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+	defer c2.Close()
+
+	c1, err := listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	defer c1.Close()
+
+	serverErr := make(chan error)
+	go func() {
+		srv := tls.Server(c1, serverConfig)
+		err := srv.Handshake()
+		if err == nil {
+			// TLS 1.3 servers verify the client certificate after the client finished the handshake
+			_, err = srv.Write([]byte{0})
+		}
+		c1.Close()
+		serverErr <- err
+	}()
+
+	clientConfig = clientConfig.Clone()
+	clientConfig.ServerName = "localhost"
+	client := tls.Client(c2, clientConfig)
+	clientErr := client.Handshake()
+	if clientErr == nil {
+		_, clientErr = client.Read(make([]byte, 1))
+	}
+	c2.Close()
+
+	if err := <-serverErr; clientErr == nil {
+		clientErr = err
+	}
+	if clientErr != nil {
+		return nil, clientErr
+	}
+
+	return client.ConnectionState().PeerCertificates[0].Raw, nil
+}
+
+func leaf(t *testing.T, certFile string, keyFile string) []byte {
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+
+	return cert.Certificate[0]
+}
+
+func replace(t *testing.T, dst string, src string) {
+
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", src, err)
+	}
+
+	if err := ioutil.WriteFile(dst, content, 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", dst, err)
+	}
+
+	// the modification time can have a coarse resolution
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(dst, future, future); err != nil {
+		t.Fatalf("Failed to change times of %s: %v", dst, err)
+	}
+}
+
+func TestNewCertificatesInvalidFiles(t *testing.T) {
+
+	f := servertest.WriteCertificates(t, t.TempDir())
+
+	_, err1 := NewCertificates(f.CertFile, f.CertFile, "")
+
+	assert.NotNil(t, err1)
+
+	_, err2 := NewCertificates(f.CertFile, f.KeyFile, f.KeyFile)
+
+	assert.Equal(t, ErrInvalidClientCA, err2)
+
+	_, err3 := NewCertificates(f.CertFile, f.KeyFile, f.CAFile+".missing")
+
+	assert.NotNil(t, err3)
+}
+
+func TestServerConfigReloadsChangedCertificate(t *testing.T) {
+
+	f1 := servertest.WriteCertificates(t, t.TempDir())
+	f2 := servertest.WriteCertificates(t, t.TempDir())
+
+	c, err := NewCertificates(f1.CertFile, f1.KeyFile, "")
+	assert.Nil(t, err)
+
+	r1, err1 := handshake(c.ServerConfig(), servertest.ClientTLSConfig(t, f1, false))
+
+	assert.Nil(t, err1)
+	assert.Equal(t, leaf(t, f1.CertFile, f1.KeyFile), r1)
+
+	replace(t, f1.CertFile, f2.CertFile)
+	replace(t, f1.KeyFile, f2.KeyFile)
+
+	r2, err2 := handshake(c.ServerConfig(), servertest.ClientTLSConfig(t, f2, false))
+
+	assert.Nil(t, err2)
+	assert.Equal(t, leaf(t, f2.CertFile, f2.KeyFile), r2)
+}
+
+func TestServerConfigKeepsPreviousCertificateOnInvalidFile(t *testing.T) {
+
+	f := servertest.WriteCertificates(t, t.TempDir())
+
+	c, err := NewCertificates(f.CertFile, f.KeyFile, "")
+	assert.Nil(t, err)
+
+	want := leaf(t, f.CertFile, f.KeyFile)
+	replace(t, f.KeyFile, f.ClientKeyFile)
+
+	r, err := handshake(c.ServerConfig(), servertest.ClientTLSConfig(t, f, false))
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, r)
+}
+
+func TestServerConfigRequiresClientCertificate(t *testing.T) {
+
+	f := servertest.WriteCertificates(t, t.TempDir())
+
+	c, err := NewCertificates(f.CertFile, f.KeyFile, f.CAFile)
+	assert.Nil(t, err)
+
+	_, err1 := handshake(c.ServerConfig(), servertest.ClientTLSConfig(t, f, false))
+
+	assert.NotNil(t, err1)
+
+	_, err2 := handshake(c.ServerConfig(), servertest.ClientTLSConfig(t, f, true))
+
+	assert.Nil(t, err2)
+}
+
+func TestLoopbackConfigPinsServerCertificate(t *testing.T) {
+
+	f1 := servertest.WriteCertificates(t, t.TempDir())
+	f2 := servertest.WriteCertificates(t, t.TempDir())
+
+	c1, err1 := NewCertificates(f1.CertFile, f1.KeyFile, f1.CAFile)
+	c2, err2 := NewCertificates(f2.CertFile, f2.KeyFile, "")
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+
+	_, err3 := handshake(c1.ServerConfig(), c1.LoopbackConfig())
+
+	assert.Nil(t, err3)
+
+	_, err4 := handshake(c2.ServerConfig(), c1.LoopbackConfig())
+
+	assert.NotNil(t, err4)
+}
+
+func TestLoopbackConfigWithServerOnlyCertificate(t *testing.T) {
+
+	f := servertest.WriteCertificates(t, t.TempDir())
+
+	// the server rejects the server certificate presented by the gateway as client certificate
+	c1, err1 := NewCertificates(f.ServerOnlyCertFile, f.ServerOnlyKeyFile, "")
+	assert.Nil(t, err1)
+	c2, err2 := NewCertificates(f.CertFile, f.KeyFile, f.CAFile)
+	assert.Nil(t, err2)
+
+	config := c1.LoopbackConfig()
+	config.VerifyPeerCertificate = nil
+
+	_, err3 := handshake(c2.ServerConfig(), config)
+
+	assert.NotNil(t, err3)
+
+	_, err4 := NewCertificates(f.ServerOnlyCertFile, f.ServerOnlyKeyFile, f.CAFile)
+
+	assert.Equal(t, ErrGatewayCertificate, err4)
+
+	c3, err5 := NewCertificates(f.ServerOnlyCertFile, f.ServerOnlyKeyFile, f.CAFile, WithGatewayCertificate(f.ClientCertFile, f.ClientKeyFile))
+	assert.Nil(t, err5)
+
+	_, err6 := handshake(c3.ServerConfig(), c3.LoopbackConfig())
+
+	assert.Nil(t, err6)
+}
+
+func TestNewCertificatesInvalidGatewayCertificate(t *testing.T) {
+
+	f := servertest.WriteCertificates(t, t.TempDir())
+
+	_, err := NewCertificates(f.CertFile, f.KeyFile, f.CAFile, WithGatewayCertificate(f.ClientCertFile, f.KeyFile))
+
+	assert.NotNil(t, err)
+}
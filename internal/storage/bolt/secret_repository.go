@@ -0,0 +1,320 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+
+	uuid "github.com/satori/go.uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var secretBucket = []byte("secret")
+
+// record is the secret stored as JSON in the bucket by its ID
+type record struct {
+	Content           string    `json:"content"`
+	CustomPwd         bool      `json:"custom_pwd"`
+	CreatedAt         time.Time `json:"created_at"`
+	ExpiredAt         time.Time `json:"expired_at"`
+	RemainingViews    int       `json:"remaining_views"`
+	FailedAttempts    int       `json:"failed_attempts"`
+	DeletionTokenHash string    `json:"deletion_token_hash"`
+}
+
+type boltSecretRepository struct {
+	DB *bolt.DB
+}
+
+// NewBoltSecretRepository returns a repository that keeps the secrets in the file path (it is created if it does not exist).
+// Only one process can open the file at a time, if it is locked we wait up to 1 second.
+func NewBoltSecretRepository(path string) (sharesecret.SecretRepository, error) {
+
+	d, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(secretBucket)
+		return err
+	})
+
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	return &boltSecretRepository{DB: d}, nil
+}
+
+// Close closes the file, another process can open it then
+func (r *boltSecretRepository) Close() error {
+	return r.DB.Close()
+}
+
+func (r *boltSecretRepository) GetSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	var secret sharesecret.Secret
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		secret, err = get(tx, id)
+		return err
+	})
+
+	return secret, err
+}
+
+func (r *boltSecretRepository) CreateSecret(ctx context.Context, content string, customPwd bool, expire time.Time, maxViews int, deletionTokenHash string) (sharesecret.Secret, error) {
+
+	u := uuid.Must(uuid.NewV4(), nil)
+	id := u.String()
+
+	secret := sharesecret.Secret{ID: id, Content: content, CustomPwd: customPwd, CreatedAt: time.Now().UTC(), ExpiredAt: expire, RemainingViews: maxViews, DeletionTokenHash: deletionTokenHash}
+
+	err := r.DB.Update(func(tx *bolt.Tx) error {
+		return put(tx, secret)
+	})
+
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+func (r *boltSecretRepository) ClaimSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	var secret sharesecret.Secret
+	// bolt allows only one read-write transaction at a time so concurrent claims are serialized
+	err := r.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		secret, err = get(tx, id)
+		if err != nil {
+			return err
+		}
+
+		secret.RemainingViews--
+		if secret.RemainingViews > 0 {
+			return put(tx, secret)
+		}
+
+		secret.RemainingViews = 0
+		return tx.Bucket(secretBucket).Delete([]byte(id))
+	})
+
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+func (r *boltSecretRepository) IncrementFailedAttempts(ctx context.Context, id string) (int, error) {
+
+	var attempts int
+	err := r.DB.Update(func(tx *bolt.Tx) error {
+		secret, err := get(tx, id)
+		if err != nil {
+			return err
+		}
+
+		secret.FailedAttempts++
+		attempts = secret.FailedAttempts
+
+		return put(tx, secret)
+	})
+
+	return attempts, err
+}
+
+func (r *boltSecretRepository) RemoveSecret(ctx context.Context, id string) error {
+
+	return r.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(secretBucket)
+		if b.Get([]byte(id)) == nil {
+			return sharesecret.ErrSecretNotFound
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+func (r *boltSecretRepository) HasSecretWithCustomPwd(ctx context.Context, id string) (bool, error) {
+
+	secret, err := r.GetSecret(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return secret.CustomPwd, nil
+}
+
+func (r *boltSecretRepository) GetSecrets(ctx context.Context) ([]sharesecret.Secret, error) {
+
+	now := time.Now().UTC()
+
+	var secrets []sharesecret.Secret
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(secretBucket).ForEach(func(k, v []byte) error {
+			secret, err := unmarshal(k, v)
+			if err != nil {
+				return err
+			}
+
+			if secret.ExpiredAt.After(now) {
+				secrets = append(secrets, secret)
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+func (r *boltSecretRepository) CountSecrets(ctx context.Context) (int64, error) {
+
+	now := time.Now().UTC()
+
+	var n int64
+	err := r.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(secretBucket).ForEach(func(k, v []byte) error {
+			secret, err := unmarshal(k, v)
+			if err != nil {
+				return err
+			}
+
+			if secret.ExpiredAt.After(now) {
+				n++
+			}
+
+			return nil
+		})
+	})
+
+	return n, err
+}
+
+func (r *boltSecretRepository) UpdateSecretContent(ctx context.Context, id string, content string) error {
+
+	return r.DB.Update(func(tx *bolt.Tx) error {
+		secret, err := get(tx, id)
+		if err != nil {
+			// the secret was seen or expired meanwhile, nothing to update
+			return nil
+		}
+
+		secret.Content = content
+
+		return put(tx, secret)
+	})
+}
+
+func (r *boltSecretRepository) RemoveSecretsExpired(ctx context.Context) ([]string, error) {
+
+	now := time.Now().UTC()
+
+	var ids []string
+	err := r.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(secretBucket)
+
+		// deleting while iterating with a cursor skips items, so first we collect the keys
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			secret, err := unmarshal(k, v)
+			if err != nil {
+				return err
+			}
+
+			if !secret.ExpiredAt.After(now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			ids = append(ids, string(k))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// get returns the secret if it is not expired
+func get(tx *bolt.Tx, id string) (sharesecret.Secret, error) {
+
+	v := tx.Bucket(secretBucket).Get([]byte(id))
+	if v == nil {
+		return sharesecret.Secret{}, sharesecret.ErrSecretNotFound
+	}
+
+	secret, err := unmarshal([]byte(id), v)
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	if !secret.ExpiredAt.After(time.Now().UTC()) {
+		return sharesecret.Secret{}, sharesecret.ErrSecretNotFound
+	}
+
+	return secret, nil
+}
+
+func put(tx *bolt.Tx, secret sharesecret.Secret) error {
+
+	v, err := json.Marshal(record{
+		Content:           secret.Content,
+		CustomPwd:         secret.CustomPwd,
+		CreatedAt:         secret.CreatedAt,
+		ExpiredAt:         secret.ExpiredAt,
+		RemainingViews:    secret.RemainingViews,
+		FailedAttempts:    secret.FailedAttempts,
+		DeletionTokenHash: secret.DeletionTokenHash,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(secretBucket).Put([]byte(secret.ID), v)
+}
+
+func unmarshal(k []byte, v []byte) (sharesecret.Secret, error) {
+
+	var rec record
+	if err := json.Unmarshal(v, &rec); err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	return sharesecret.Secret{
+		ID:                string(k),
+		Content:           rec.Content,
+		CustomPwd:         rec.CustomPwd,
+		CreatedAt:         rec.CreatedAt,
+		ExpiredAt:         rec.ExpiredAt,
+		RemainingViews:    rec.RemainingViews,
+		FailedAttempts:    rec.FailedAttempts,
+		DeletionTokenHash: rec.DeletionTokenHash,
+	}, nil
+}
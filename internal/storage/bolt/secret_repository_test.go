@@ -0,0 +1,47 @@
+// +build unit
+
+package bolt
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/storage/storagetest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoltSecretRepository(t *testing.T) {
+
+	storagetest.TestSecretRepository(t, func() sharesecret.SecretRepository {
+		r, err := NewBoltSecretRepository(filepath.Join(t.TempDir(), "sharesecret.db"))
+		if err != nil {
+			t.Fatalf("NewBoltSecretRepository failed: %v", err)
+		}
+		return r
+	})
+}
+
+func TestBoltSecretRepositoryKeepsSecretsAfterReopen(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "sharesecret.db")
+
+	r1, err1 := NewBoltSecretRepository(path)
+	assert.Nil(t, err1)
+
+	s, err := r1.CreateSecret(context.Background(), "this is a test reopen", false, time.Now().UTC().Add(time.Hour), 1, "")
+	assert.Nil(t, err)
+	assert.Nil(t, r1.(io.Closer).Close())
+
+	r2, err2 := NewBoltSecretRepository(path)
+	assert.Nil(t, err2)
+
+	r3, err3 := r2.GetSecret(context.Background(), s.ID)
+
+	assert.Nil(t, err3)
+	assert.Equal(t, "this is a test reopen", r3.Content)
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type memorySecretRepository struct {
+	mu      sync.Mutex
+	secrets map[string]sharesecret.Secret
+}
+
+// NewMemorySecretRepository returns a repository that keeps the secrets in memory, they are lost when the process ends
+func NewMemorySecretRepository() sharesecret.SecretRepository {
+	return &memorySecretRepository{secrets: make(map[string]sharesecret.Secret)}
+}
+
+func (r *memorySecretRepository) GetSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.get(id)
+}
+
+func (r *memorySecretRepository) CreateSecret(ctx context.Context, content string, customPwd bool, expire time.Time, maxViews int, deletionTokenHash string) (sharesecret.Secret, error) {
+
+	u := uuid.Must(uuid.NewV4(), nil)
+	id := u.String()
+
+	secret := sharesecret.Secret{ID: id, Content: content, CustomPwd: customPwd, CreatedAt: time.Now().UTC(), ExpiredAt: expire, RemainingViews: maxViews, DeletionTokenHash: deletionTokenHash}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.secrets[id] = secret
+
+	return secret, nil
+}
+
+func (r *memorySecretRepository) ClaimSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	secret, err := r.get(id)
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	secret.RemainingViews--
+	if secret.RemainingViews > 0 {
+		r.secrets[id] = secret
+	} else {
+		secret.RemainingViews = 0
+		delete(r.secrets, id)
+	}
+
+	return secret, nil
+}
+
+func (r *memorySecretRepository) IncrementFailedAttempts(ctx context.Context, id string) (int, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	secret, err := r.get(id)
+	if err != nil {
+		return 0, err
+	}
+
+	secret.FailedAttempts++
+	r.secrets[id] = secret
+
+	return secret.FailedAttempts, nil
+}
+
+func (r *memorySecretRepository) RemoveSecret(ctx context.Context, id string) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.secrets[id]; !ok {
+		return sharesecret.ErrSecretNotFound
+	}
+
+	delete(r.secrets, id)
+
+	return nil
+}
+
+func (r *memorySecretRepository) HasSecretWithCustomPwd(ctx context.Context, id string) (bool, error) {
+
+	secret, err := r.GetSecret(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return secret.CustomPwd, nil
+}
+
+func (r *memorySecretRepository) GetSecrets(ctx context.Context) ([]sharesecret.Secret, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	var secrets []sharesecret.Secret
+	for _, secret := range r.secrets {
+		if secret.ExpiredAt.After(now) {
+			secrets = append(secrets, secret)
+		}
+	}
+
+	return secrets, nil
+}
+
+func (r *memorySecretRepository) CountSecrets(ctx context.Context) (int64, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	var n int64
+	for _, secret := range r.secrets {
+		if secret.ExpiredAt.After(now) {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (r *memorySecretRepository) UpdateSecretContent(ctx context.Context, id string, content string) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if secret, ok := r.secrets[id]; ok {
+		secret.Content = content
+		r.secrets[id] = secret
+	}
+
+	return nil
+}
+
+func (r *memorySecretRepository) RemoveSecretsExpired(ctx context.Context) ([]string, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	var ids []string
+	for id, secret := range r.secrets {
+		if !secret.ExpiredAt.After(now) {
+			delete(r.secrets, id)
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// get returns the secret if it is not expired, the caller must hold the lock
+func (r *memorySecretRepository) get(id string) (sharesecret.Secret, error) {
+
+	secret, ok := r.secrets[id]
+	if !ok || !secret.ExpiredAt.After(time.Now().UTC()) {
+		return sharesecret.Secret{}, sharesecret.ErrSecretNotFound
+	}
+
+	return secret, nil
+}
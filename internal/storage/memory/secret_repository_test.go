@@ -0,0 +1,38 @@
+// +build unit
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/storage/storagetest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySecretRepository(t *testing.T) {
+
+	storagetest.TestSecretRepository(t, func() sharesecret.SecretRepository {
+		return NewMemorySecretRepository()
+	})
+}
+
+func TestMemorySecretRepositoryRemoveSecretsExpired(t *testing.T) {
+
+	r := NewMemorySecretRepository()
+
+	r0, _ := r.CreateSecret(context.Background(), "this is a test remove secret expired", false, time.Now().UTC().Add(-1*time.Hour), 1, "")
+	r1, _ := r.CreateSecret(context.Background(), "this is a test remove secret not expired", false, time.Now().UTC().Add(time.Hour), 1, "")
+
+	r2, err2 := r.RemoveSecretsExpired(context.Background())
+
+	assert.Nil(t, err2)
+	assert.Equal(t, []string{r0.ID}, r2)
+
+	_, err3 := r.GetSecret(context.Background(), r1.ID)
+
+	assert.Nil(t, err3)
+}
@@ -0,0 +1,303 @@
+// Package migration applies versioned SQL migrations and records them in the schema_migrations table.
+//
+// Migrations are files named "<version>_<name>.up.sql" and "<version>_<name>.down.sql" (e.g. 0001_create_secret.up.sql),
+// statements are separated by ";" at the end of a line.
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// All errors reported by the migrations
+var (
+	ErrInvalidMigration = errors.New("migration files should be named <version>_<name>.up.sql or <version>_<name>.down.sql")
+	ErrMissingMigration = errors.New("migration should have up and down files")
+	ErrNoMigration      = errors.New("there is no migration applied")
+)
+
+const createTable = "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, applied_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+
+var (
+	fileName     = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	endStatement = regexp.MustCompile(`;\s*(\n|$)`)
+)
+
+// Migration is a version of the schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied, AppliedAt is zero if it is pending
+type Status struct {
+	Migration
+	AppliedAt time.Time
+}
+
+// Lock has the statements that take and release a lock of the database session (e.g. GET_LOCK in MySQL), the
+// migrations are applied holding it so processes started at the same time (e.g. replicas migrating on startup)
+// wait for the first one instead of applying them twice. A zero Lock does not lock.
+type Lock struct {
+	Acquire string
+	Release string
+}
+
+// Migrator applies the migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	lock       Lock
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator with the migrations of the directory dir in fsys, Up and Down hold lock
+func NewMigrator(db *sql.DB, fsys fs.FS, dir string, lock Lock) (*Migrator, error) {
+
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, lock: lock, migrations: migrations}, nil
+}
+
+// Load returns the migrations of the directory dir in fsys sorted by version
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), ErrInvalidMigration)
+		}
+
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), ErrInvalidMigration)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("version %d is used by %s and %s: %w", version, migration.Name, m[2], ErrInvalidMigration)
+		}
+
+		if m[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(strings.TrimSpace(m.Up)) == 0 || len(strings.TrimSpace(m.Down)) == 0 {
+			return nil, fmt.Errorf("%d_%s: %w", m.Version, m.Name, ErrMissingMigration)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies the pending migrations and returns them
+func (m *Migrator) Up() ([]Migration, error) {
+
+	conn, err := m.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer m.release(conn)
+
+	applied, err := applied(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		insert := fmt.Sprintf("INSERT INTO schema_migrations (version) VALUES (%d)", migration.Version)
+		if err := exec(conn, migration.Up, insert); err != nil {
+			return done, fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts the last applied migration and returns it
+func (m *Migrator) Down() (Migration, error) {
+
+	conn, err := m.acquire()
+	if err != nil {
+		return Migration{}, err
+	}
+	defer m.release(conn)
+
+	applied, err := applied(conn)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		remove := fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %d", migration.Version)
+		if err := exec(conn, migration.Down, remove); err != nil {
+			return Migration{}, fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+		}
+
+		return migration, nil
+	}
+
+	return Migration{}, ErrNoMigration
+}
+
+// Status returns all migrations with the time they were applied
+func (m *Migrator) Status() ([]Status, error) {
+
+	conn, err := m.db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := applied(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status = append(status, Status{Migration: migration, AppliedAt: applied[migration.Version]})
+	}
+
+	return status, nil
+}
+
+// Close closes the database
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// acquire returns a connection holding the lock, the lock is released by the database if the connection is lost.
+// The migrations are applied with the same connection, the pool may have only one.
+func (m *Migrator) acquire() (*sql.Conn, error) {
+
+	conn, err := m.db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.lock.Acquire) > 0 {
+		if _, err := conn.ExecContext(context.Background(), m.lock.Acquire); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("lock migrations: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (m *Migrator) release(conn *sql.Conn) {
+
+	if len(m.lock.Release) > 0 {
+		_, _ = conn.ExecContext(context.Background(), m.lock.Release)
+	}
+	conn.Close()
+}
+
+func applied(conn *sql.Conn) (map[int64]time.Time, error) {
+
+	if _, err := conn.ExecContext(context.Background(), createTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// exec executes the statements of the migration and then record in a transaction.
+// MySQL commits the statements that change the schema (e.g. CREATE TABLE) implicitly, so
+// keep a statement per migration there to not leave a migration half applied.
+func exec(conn *sql.Conn, migration string, record string) error {
+
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range append(Statements(migration), record) {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Statements splits the content of a migration file in statements
+func Statements(content string) []string {
+
+	var statements []string
+	for _, s := range endStatement.Split(content, -1) {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			statements = append(statements, s)
+		}
+	}
+
+	return statements
+}
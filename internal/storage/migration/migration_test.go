@@ -0,0 +1,174 @@
+// +build unit
+
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSortedByVersion(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"migrations/0002_add_index.up.sql":       {Data: []byte("CREATE INDEX idx ON secret (expired_at);")},
+		"migrations/0002_add_index.down.sql":     {Data: []byte("DROP INDEX idx ON secret;")},
+		"migrations/0001_create_secret.up.sql":   {Data: []byte("CREATE TABLE secret (id varchar(36));")},
+		"migrations/0001_create_secret.down.sql": {Data: []byte("DROP TABLE secret;")},
+	}
+
+	migrations, err := Load(fsys, "migrations")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "create_secret", Up: "CREATE TABLE secret (id varchar(36));", Down: "DROP TABLE secret;"},
+		{Version: 2, Name: "add_index", Up: "CREATE INDEX idx ON secret (expired_at);", Down: "DROP INDEX idx ON secret;"},
+	}, migrations)
+}
+
+func TestLoadInvalidFileName(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"migrations/create_secret.up.sql": {Data: []byte("CREATE TABLE secret (id varchar(36));")},
+	}
+
+	_, err := Load(fsys, "migrations")
+
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+}
+
+func TestLoadSameVersionDifferentName(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_secret.up.sql":  {Data: []byte("CREATE TABLE secret (id varchar(36));")},
+		"migrations/0001_create_other.down.sql": {Data: []byte("DROP TABLE secret;")},
+	}
+
+	_, err := Load(fsys, "migrations")
+
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+}
+
+func TestLoadMissingDown(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_secret.up.sql": {Data: []byte("CREATE TABLE secret (id varchar(36));")},
+	}
+
+	_, err := Load(fsys, "migrations")
+
+	assert.ErrorIs(t, err, ErrMissingMigration)
+}
+
+func TestStatements(t *testing.T) {
+
+	content := `
+ALTER TABLE secret ADD COLUMN a int;
+ALTER TABLE secret ADD COLUMN b varchar(10) DEFAULT ';';
+
+ALTER TABLE secret ADD COLUMN c int
+`
+
+	assert.Equal(t, []string{
+		"ALTER TABLE secret ADD COLUMN a int",
+		"ALTER TABLE secret ADD COLUMN b varchar(10) DEFAULT ';'",
+		"ALTER TABLE secret ADD COLUMN c int",
+	}, Statements(content))
+}
+
+// fakeConnector is a database without migrations applied that records the statements of its connections
+type fakeConnector struct {
+	mu         sync.Mutex
+	conns      int
+	statements []string
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conns++
+
+	return fakeConn{c}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (c *fakeConnector) record(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, query)
+}
+
+type fakeConn struct {
+	c *fakeConnector
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.c, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeStmt struct {
+	c     *fakeConnector
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.c.record(s.query)
+	return driver.RowsAffected(0), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.c.record(s.query)
+	return fakeRows{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"version", "applied_at"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestUpHoldsLock(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_secret.up.sql":   {Data: []byte("CREATE TABLE secret (id varchar(36));")},
+		"migrations/0001_create_secret.down.sql": {Data: []byte("DROP TABLE secret;")},
+	}
+
+	c := &fakeConnector{}
+	db := sql.OpenDB(c)
+	// the migrations are applied with the connection holding the lock
+	db.SetMaxOpenConns(1)
+
+	m, err := NewMigrator(db, fsys, "migrations", Lock{Acquire: "SELECT GET_LOCK('m', -1)", Release: "SELECT RELEASE_LOCK('m')"})
+	assert.Nil(t, err)
+
+	done, err := m.Up()
+
+	assert.Nil(t, err)
+	assert.Len(t, done, 1)
+	assert.Equal(t, []string{
+		"SELECT GET_LOCK('m', -1)",
+		createTable,
+		"SELECT version, applied_at FROM schema_migrations",
+		"CREATE TABLE secret (id varchar(36))",
+		"INSERT INTO schema_migrations (version) VALUES (1)",
+		"SELECT RELEASE_LOCK('m')",
+	}, c.statements)
+}
@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"time"
+
+	"github.com/bernardosecades/sharesecret/internal/storage"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+// Config configures the connection to MySQL. DSN (e.g. "user:pass@tcp(host:3306)/sharesecret") has priority
+// over Name, User, Pass, Host and Port. Pool settings with zero value keep the defaults of database/sql.
+type Config struct {
+	DSN string
+
+	Name string
+	User string
+	Pass string
+	Host string
+	Port string
+
+	// TLS is the tls parameter of the driver: "true", "skip-verify", "preferred" or a config registered with mysql.RegisterTLSConfig
+	TLS string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// PingRetries is the number of pings after the first one fails, waiting PingBackoff and doubling it (up to
+	// storage.MaxPingBackoff) between them
+	PingRetries int
+	PingBackoff time.Duration
+}
+
+// dataSourceName returns the DSN of the config, times are always parsed to time.Time
+func (c Config) dataSourceName() (string, error) {
+
+	cfg := driver.NewConfig()
+	if len(c.DSN) > 0 {
+		var err error
+		if cfg, err = driver.ParseDSN(c.DSN); err != nil {
+			return "", err
+		}
+	} else {
+		cfg.User = c.User
+		cfg.Passwd = c.Pass
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(c.Host, c.Port)
+		cfg.DBName = c.Name
+		cfg.Params = map[string]string{"charset": "utf8"}
+	}
+
+	if len(c.TLS) > 0 {
+		cfg.TLSConfig = c.TLS
+	}
+	cfg.ParseTime = true
+
+	return cfg.FormatDSN(), nil
+}
+
+func open(ctx context.Context, c Config) (*sql.DB, error) {
+
+	dsn, err := c.dataSourceName()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.MaxOpenConns > 0 {
+		d.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		d.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		d.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+
+	if err := storage.Ping(ctx, d, c.PingRetries, c.PingBackoff); err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
@@ -0,0 +1,67 @@
+// +build unit
+
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigDataSourceNameFromParts(t *testing.T) {
+
+	c := Config{Name: "sharesecret", User: "berni", Pass: "1234", Host: "127.0.0.1", Port: "3308", TLS: "skip-verify"}
+
+	dsn, err := c.dataSourceName()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "berni:1234@tcp(127.0.0.1:3308)/sharesecret?parseTime=true&tls=skip-verify&charset=utf8", dsn)
+}
+
+func TestConfigDataSourceNameFromDSN(t *testing.T) {
+
+	c := Config{DSN: "berni:1234@tcp(mysql:3306)/sharesecret", Host: "127.0.0.1", Port: "3308"}
+
+	dsn, err := c.dataSourceName()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "berni:1234@tcp(mysql:3306)/sharesecret?parseTime=true", dsn)
+}
+
+func TestConfigDataSourceNameInvalidDSN(t *testing.T) {
+
+	c := Config{DSN: "berni:1234@tcp(mysql:3306)"}
+
+	_, err := c.dataSourceName()
+
+	assert.NotNil(t, err)
+}
+
+func TestNewMySQLSecretRepositoryReturnsErrorAfterPingRetries(t *testing.T) {
+
+	c := Config{Name: "sharesecret", User: "berni", Pass: "1234", Host: "127.0.0.1", Port: "1", PingRetries: 2, PingBackoff: 10 * time.Millisecond}
+
+	start := time.Now()
+	r, err := NewMySQLSecretRepository(context.Background(), c)
+
+	assert.Nil(t, r)
+	assert.NotNil(t, err)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(30*time.Millisecond))
+}
+
+func TestNewMySQLSecretRepositoryStopsPingRetriesWhenContextIsDone(t *testing.T) {
+
+	c := Config{Name: "sharesecret", User: "berni", Pass: "1234", Host: "127.0.0.1", Port: "1", PingRetries: 10, PingBackoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	r, err := NewMySQLSecretRepository(ctx, c)
+
+	assert.Nil(t, r)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS secret (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    content text NOT NULL,
+    custom_pwd bool NOT NULL default 0,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expired_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
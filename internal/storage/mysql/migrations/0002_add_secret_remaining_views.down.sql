@@ -0,0 +1 @@
+ALTER TABLE secret DROP COLUMN remaining_views;
@@ -0,0 +1 @@
+ALTER TABLE secret ADD COLUMN remaining_views int NOT NULL default 1;
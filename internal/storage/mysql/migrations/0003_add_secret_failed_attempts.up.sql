@@ -0,0 +1 @@
+ALTER TABLE secret ADD COLUMN failed_attempts int NOT NULL default 0;
@@ -0,0 +1 @@
+ALTER TABLE secret DROP COLUMN deletion_token_hash;
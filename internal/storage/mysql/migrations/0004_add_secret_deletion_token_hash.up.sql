@@ -0,0 +1 @@
+ALTER TABLE secret ADD COLUMN deletion_token_hash varchar(64) NOT NULL default '';
@@ -0,0 +1 @@
+DROP INDEX secret_expired_at_idx ON secret;
@@ -0,0 +1 @@
+CREATE INDEX secret_expired_at_idx ON secret (expired_at);
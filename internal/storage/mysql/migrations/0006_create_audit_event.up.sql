@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS audit_event (
+    id bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    occurred_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    event varchar(32) NOT NULL,
+    secret_id varchar(36) NOT NULL default '',
+    count bigint NOT NULL default 0
+);
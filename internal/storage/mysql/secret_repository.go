@@ -1,51 +1,87 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"embed"
 	"errors"
-	"fmt"
 	"time"
 
 	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/audit"
+	"github.com/bernardosecades/sharesecret/internal/storage/migration"
 
-	_ "github.com/go-sql-driver/mysql"
 	uuid "github.com/satori/go.uuid"
 )
 
-const formatDate = "2006-01-02 15:04:05"
+const (
+	formatDate    = "2006-01-02 15:04:05"
+	secretColumns = "id, content, custom_pwd, created_at, expired_at, remaining_views, failed_attempts, deletion_token_hash"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLock is a named lock of MySQL, -1 waits for it without timeout
+var migrationLock = migration.Lock{
+	Acquire: "SELECT GET_LOCK('sharesecret_migrations', -1)",
+	Release: "SELECT RELEASE_LOCK('sharesecret_migrations')",
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
 type mySQLSecretRepository struct {
 	SQL *sql.DB
 }
 
-func NewMySQLSecretRepository(dbName string, dbUser string, dbPass string, dbHost string, dbPort string) sharesecret.SecretRepository {
-	dbSource := fmt.Sprintf(
-		"%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=true",
-		dbUser,
-		dbPass,
-		dbHost,
-		dbPort,
-		dbName,
-	)
-	d, err := sql.Open("mysql", dbSource)
+// NewMySQLSecretRepository returns a repository connected to MySQL, an error if it can not connect before ctx is done
+func NewMySQLSecretRepository(ctx context.Context, c Config) (sharesecret.SecretRepository, error) {
+	d, err := open(ctx, c)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	err = d.Ping() // Need to do this to check that the connection is valid
+	return &mySQLSecretRepository{SQL: d}, nil
+}
+
+// NewMigrator returns a migrator with the migrations of the secret and audit_event tables
+func NewMigrator(ctx context.Context, c Config) (*migration.Migrator, error) {
+	d, err := open(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migration.NewMigrator(d, migrations, "migrations", migrationLock)
 	if err != nil {
-		panic(err)
+		d.Close()
+		return nil, err
 	}
 
-	return &mySQLSecretRepository{SQL: d}
+	return m, nil
 }
 
-func (r *mySQLSecretRepository) GetSecret(id string) (sharesecret.Secret, error) {
+// Close closes the connections of the pool
+func (r *mySQLSecretRepository) Close() error {
+	return r.SQL.Close()
+}
 
-	res := r.SQL.QueryRow("SELECT * FROM secret WHERE id = ? AND expired_at > ?", id, time.Now().UTC().Format(formatDate))
+func (r *mySQLSecretRepository) GetSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
 
-	var secret sharesecret.Secret
-	err := res.Scan(&secret.ID, &secret.Content, &secret.CustomPwd, &secret.CreatedAt, &secret.ExpiredAt)
+	res := queryRowContext(ctx, r.SQL, "SELECT "+secretColumns+" FROM secret WHERE id = ? AND expired_at > ?", id, time.Now().UTC().Format(formatDate))
+
+	return scanSecret(res)
+}
+
+func (r *mySQLSecretRepository) CreateSecret(ctx context.Context, content string, customPwd bool, expire time.Time, maxViews int, deletionTokenHash string) (sharesecret.Secret, error) {
+
+	u := uuid.Must(uuid.NewV4(), nil)
+	id := u.String()
+
+	secret := sharesecret.Secret{ID: id, Content: content, CustomPwd: customPwd, CreatedAt: time.Now().UTC(), ExpiredAt: expire, RemainingViews: maxViews, DeletionTokenHash: deletionTokenHash}
+
+	_, err := execContext(ctx, r.SQL, "INSERT INTO secret ("+secretColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)", secret.ID, secret.Content, secret.CustomPwd, secret.CreatedAt.Format(formatDate), secret.ExpiredAt.Format(formatDate), secret.RemainingViews, secret.FailedAttempts, secret.DeletionTokenHash)
 
 	if err != nil {
 		return sharesecret.Secret{}, err
@@ -54,39 +90,84 @@ func (r *mySQLSecretRepository) GetSecret(id string) (sharesecret.Secret, error)
 	return secret, nil
 }
 
-func (r *mySQLSecretRepository) CreateSecret(content string, customPwd bool, expire time.Time) (sharesecret.Secret, error) {
+func (r *mySQLSecretRepository) RemoveSecret(ctx context.Context, id string) error {
 
-	u := uuid.Must(uuid.NewV4(), nil)
-	id := u.String()
+	res, err := execContext(ctx, r.SQL, "DELETE FROM secret WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
 
-	secret := sharesecret.Secret{ID: id, Content: content, CustomPwd: customPwd, CreatedAt: time.Now().UTC(), ExpiredAt: expire}
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
 
-	_, err := r.SQL.Exec("INSERT INTO secret (id, content, custom_pwd, created_at, expired_at) VALUES (?, ?, ?, ?, ?)", secret.ID, secret.Content, secret.CustomPwd, secret.CreatedAt.Format(formatDate), secret.ExpiredAt.Format(formatDate))
+	if ra == 0 {
+		return sharesecret.ErrSecretNotFound
+	}
+
+	return nil
+}
 
+func (r *mySQLSecretRepository) ClaimSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	tx, err := r.SQL.BeginTx(ctx, nil)
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+	defer tx.Rollback()
+
+	// the row stays locked until the end of the transaction so concurrent claims wait and then see the view consumed
+	res := queryRowContext(ctx, tx, "SELECT "+secretColumns+" FROM secret WHERE id = ? AND expired_at > ? FOR UPDATE", id, time.Now().UTC().Format(formatDate))
+	secret, err := scanSecret(res)
 	if err != nil {
 		return sharesecret.Secret{}, err
 	}
 
+	secret.RemainingViews--
+	if secret.RemainingViews > 0 {
+		_, err = execContext(ctx, tx, "UPDATE secret SET remaining_views = ? WHERE id = ?", secret.RemainingViews, id)
+	} else {
+		secret.RemainingViews = 0
+		_, err = execContext(ctx, tx, "DELETE FROM secret WHERE id = ?", id)
+	}
+
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	if err := commit(ctx, tx); err != nil {
+		return sharesecret.Secret{}, err
+	}
+
 	return secret, nil
 }
 
-func (r *mySQLSecretRepository) RemoveSecret(id string) error {
+func (r *mySQLSecretRepository) IncrementFailedAttempts(ctx context.Context, id string) (int, error) {
 
-	res, err := r.SQL.Exec("DELETE FROM secret WHERE id = ?", id)
+	tx, err := r.SQL.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	if ra, err := res.RowsAffected(); err != nil || ra == 0 {
-		return errors.New("we can not delete the secret")
+	var attempts int
+	err = queryRowContext(ctx, tx, "SELECT failed_attempts FROM secret WHERE id = ? AND expired_at > ? FOR UPDATE", id, time.Now().UTC().Format(formatDate)).Scan(&attempts)
+	if err != nil {
+		return 0, notFound(err)
 	}
 
-	return nil
+	attempts++
+	if _, err := execContext(ctx, tx, "UPDATE secret SET failed_attempts = ? WHERE id = ?", attempts, id); err != nil {
+		return 0, err
+	}
+
+	return attempts, commit(ctx, tx)
 }
 
-func (r *mySQLSecretRepository) HasSecretWithCustomPwd(id string) (bool, error) {
+func (r *mySQLSecretRepository) HasSecretWithCustomPwd(ctx context.Context, id string) (bool, error) {
 
-	secret, err := r.GetSecret(id)
+	secret, err := r.GetSecret(ctx, id)
 	if err != nil {
 		return false, err
 	}
@@ -94,12 +175,112 @@ func (r *mySQLSecretRepository) HasSecretWithCustomPwd(id string) (bool, error)
 	return secret.CustomPwd, nil
 }
 
-func (r *mySQLSecretRepository) RemoveSecretsExpired() (int64, error) {
+func (r *mySQLSecretRepository) GetSecrets(ctx context.Context) ([]sharesecret.Secret, error) {
 
-	re, err := r.SQL.Exec("DELETE FROM secret WHERE expired_at <= ?", time.Now().UTC().Format(formatDate))
+	rows, err := queryContext(ctx, r.SQL, "SELECT "+secretColumns+" FROM secret WHERE expired_at > ?", time.Now().UTC().Format(formatDate))
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []sharesecret.Secret
+	for rows.Next() {
+		secret, err := scanSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, rows.Err()
+}
+
+func (r *mySQLSecretRepository) UpdateSecretContent(ctx context.Context, id string, content string) error {
+
+	_, err := execContext(ctx, r.SQL, "UPDATE secret SET content = ? WHERE id = ?", content, id)
+
+	return err
+}
+
+func (r *mySQLSecretRepository) RemoveSecretsExpired(ctx context.Context) ([]string, error) {
+
+	tx, err := r.SQL.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// MySQL has no DELETE ... RETURNING, the rows stay locked until they are deleted
+	now := time.Now().UTC().Format(formatDate)
+	ids, err := queryIDs(ctx, tx, "SELECT id FROM secret WHERE expired_at <= ? FOR UPDATE", now)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	if _, err := execContext(ctx, tx, "DELETE FROM secret WHERE expired_at <= ?", now); err != nil {
+		return nil, err
+	}
+
+	if err := commit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func queryIDs(ctx context.Context, c conn, query string, args ...interface{}) ([]string, error) {
+
+	rows, err := queryContext(ctx, c, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *mySQLSecretRepository) CountSecrets(ctx context.Context) (int64, error) {
+
+	var n int64
+	err := queryRowContext(ctx, r.SQL, "SELECT COUNT(*) FROM secret WHERE expired_at > ?", time.Now().UTC().Format(formatDate)).Scan(&n)
+
+	return n, err
+}
+
+// Record stores the event in the audit_event table, the repository is an audit.Sink
+func (r *mySQLSecretRepository) Record(ctx context.Context, e audit.Event) error {
+
+	_, err := execContext(ctx, r.SQL, "INSERT INTO audit_event (occurred_at, event, secret_id, count) VALUES (?, ?, ?, ?)", e.Time.UTC().Format(formatDate), string(e.Type), e.SecretID, e.Count)
+
+	return err
+}
+
+func scanSecret(s scanner) (sharesecret.Secret, error) {
+
+	var secret sharesecret.Secret
+	err := s.Scan(&secret.ID, &secret.Content, &secret.CustomPwd, &secret.CreatedAt, &secret.ExpiredAt, &secret.RemainingViews, &secret.FailedAttempts, &secret.DeletionTokenHash)
+
+	if err != nil {
+		return sharesecret.Secret{}, notFound(err)
+	}
+
+	return secret, nil
+}
+
+// notFound returns sharesecret.ErrSecretNotFound when the query returned no row
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return sharesecret.ErrSecretNotFound
 	}
 
-	return re.RowsAffected()
+	return err
 }
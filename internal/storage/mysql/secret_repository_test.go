@@ -3,9 +3,14 @@
 package mysql
 
 import (
+	"context"
 	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/audit"
+	"github.com/bernardosecades/sharesecret/internal/storage/storagetest"
+	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"sync"
 	"testing"
 	"time"
 )
@@ -14,34 +19,52 @@ var mr sharesecret.SecretRepository
 
 func init() {
 
-	dbName := os.Getenv("DB_NAME")
-	dbPass := os.Getenv("DB_PASS")
-	dbUser := os.Getenv("DB_USER")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
+	m, err := NewMigrator(context.Background(), config())
+	if err != nil {
+		panic(err)
+	}
+	defer m.Close()
 
-	mr = NewMySQLSecretRepository(dbName, dbUser, dbPass, dbHost, dbPort)
+	if _, err := m.Up(); err != nil {
+		panic(err)
+	}
+
+	mr, err = NewMySQLSecretRepository(context.Background(), config())
+	if err != nil {
+		panic(err)
+	}
+}
+
+func config() Config {
+	return Config{
+		Name: os.Getenv("DB_NAME"),
+		User: os.Getenv("DB_USER"),
+		Pass: os.Getenv("DB_PASS"),
+		Host: os.Getenv("DB_HOST"),
+		Port: os.Getenv("DB_PORT"),
+	}
 }
 
 func TestMySQLSecretRepositoryCreateAndReadSecretNoExpired(t *testing.T) {
 
 	tm := time.Now().UTC().Add(time.Hour)
-	r1, err1 := mr.CreateSecret("this is a test create and read secret not expired", true, tm)
+	r1, err1 := mr.CreateSecret(context.Background(), "this is a test create and read secret not expired", true, tm, 1, "03ac674216f3e15c761ee1a5e255f067953623c8b388b4459e13f978d7c846f4")
 
 	assert.Nil(t, err1)
 	assert.NotNil(t, r1)
 
-	r2, err2 := mr.HasSecretWithCustomPwd(r1.ID)
+	r2, err2 := mr.HasSecretWithCustomPwd(context.Background(), r1.ID)
 
 	assert.Nil(t, err2)
 	assert.True(t, r2)
 
-	r3, err3 := mr.GetSecret(r1.ID)
+	r3, err3 := mr.GetSecret(context.Background(), r1.ID)
 
 	assert.Nil(t, err3)
 	assert.Equal(t, "this is a test create and read secret not expired", r3.Content)
+	assert.Equal(t, "03ac674216f3e15c761ee1a5e255f067953623c8b388b4459e13f978d7c846f4", r3.DeletionTokenHash)
 
-	err4 := mr.RemoveSecret(r3.ID)
+	err4 := mr.RemoveSecret(context.Background(), r3.ID)
 
 	assert.Nil(t, err4)
 }
@@ -49,22 +72,139 @@ func TestMySQLSecretRepositoryCreateAndReadSecretNoExpired(t *testing.T) {
 func TestMySQLSecretRepositoryCreateAndReadSecretExpired(t *testing.T) {
 
 	tm := time.Now().UTC().Add(-1 * time.Hour)
-	r1, err1 := mr.CreateSecret("this is a test create and read secret not expired", true, tm)
+	r1, err1 := mr.CreateSecret(context.Background(), "this is a test create and read secret not expired", true, tm, 1, "")
 
 	assert.Nil(t, err1)
 	assert.NotNil(t, r1)
 
-	r2, err2 := mr.HasSecretWithCustomPwd(r1.ID)
+	r2, err2 := mr.HasSecretWithCustomPwd(context.Background(), r1.ID)
 
 	assert.NotNil(t, err2)
 	assert.False(t, r2)
 
-	_, err3 := mr.GetSecret(r1.ID)
+	_, err3 := mr.GetSecret(context.Background(), r1.ID)
 
 	assert.NotNil(t, err3)
 
-	r4, err4 := mr.RemoveSecretsExpired()
+	r4, err4 := mr.RemoveSecretsExpired(context.Background())
 
 	assert.Nil(t, err4)
-	assert.Equal(t, int64(1), r4)
+	assert.Equal(t, []string{r1.ID}, r4)
+}
+
+func TestMySQLSecretRepository(t *testing.T) {
+
+	storagetest.TestSecretRepository(t, func() sharesecret.SecretRepository {
+		return mr
+	})
+}
+
+func TestMySQLMigrationsApplied(t *testing.T) {
+
+	m, err := NewMigrator(context.Background(), config())
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	defer m.Close()
+
+	status, err := m.Status()
+
+	assert.Nil(t, err)
+	for _, s := range status {
+		assert.False(t, s.AppliedAt.IsZero(), "migration %d_%s is pending", s.Version, s.Name)
+	}
+}
+
+// TestMySQLMigrationsUpgradeBaselineSchema starts from the table created by the old schema.sql with a secret of
+// that time, and applies the migrations from two processes at the same time as replicas starting together
+func TestMySQLMigrationsUpgradeBaselineSchema(t *testing.T) {
+
+	// in a database of its own, the other tests of the package use the shared one at the same time
+	cfg := config()
+	cfg.Name = "sharesecret_upgrade_" + uuid.Must(uuid.NewV4(), nil).String()[:8]
+
+	shared := mr.(*mySQLSecretRepository).SQL
+	if _, err := shared.Exec("CREATE DATABASE " + cfg.Name); err != nil {
+		t.Fatalf("Failed to create the database: %v", err)
+	}
+	defer shared.Exec("DROP DATABASE " + cfg.Name)
+
+	r, err := NewMySQLSecretRepository(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewMySQLSecretRepository failed: %v", err)
+	}
+	defer r.(*mySQLSecretRepository).Close()
+
+	m, err := NewMigrator(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	defer m.Close()
+
+	db := r.(*mySQLSecretRepository).SQL
+	if _, err := db.Exec(`CREATE TABLE secret (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    content text NOT NULL,
+    custom_pwd bool NOT NULL default 0,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expired_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`); err != nil {
+		t.Fatalf("Failed to create the baseline schema: %v", err)
+	}
+
+	id := uuid.Must(uuid.NewV4(), nil).String()
+	expire := time.Now().UTC().Add(time.Hour).Format(formatDate)
+	if _, err := db.Exec("INSERT INTO secret (id, content, created_at, expired_at) VALUES (?, ?, ?, ?)", id, "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922", time.Now().UTC().Format(formatDate), expire); err != nil {
+		t.Fatalf("Failed to insert the baseline secret: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			replica, err := NewMigrator(context.Background(), cfg)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer replica.Close()
+
+			_, errs[i] = replica.Up()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Nil(t, errs[0])
+	assert.Nil(t, errs[1])
+
+	status, err := m.Status()
+
+	assert.Nil(t, err)
+	for _, s := range status {
+		assert.False(t, s.AppliedAt.IsZero(), "migration %d_%s is pending", s.Version, s.Name)
+	}
+
+	secret, err := r.GetSecret(context.Background(), id)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, secret.RemainingViews)
+	assert.Equal(t, 0, secret.FailedAttempts)
+	assert.Empty(t, secret.DeletionTokenHash)
+}
+
+func TestMySQLRecordAuditEvent(t *testing.T) {
+
+	id := uuid.Must(uuid.NewV4(), nil).String()
+	err1 := mr.(audit.Sink).Record(context.Background(), audit.NewEvent(audit.EventCreated, id))
+
+	assert.Nil(t, err1)
+
+	var event string
+	err2 := mr.(*mySQLSecretRepository).SQL.QueryRow("SELECT event FROM audit_event WHERE secret_id = ?", id).Scan(&event)
+
+	assert.Nil(t, err2)
+	assert.Equal(t, string(audit.EventCreated), event)
 }
@@ -0,0 +1,78 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer of the statements sent to MySQL
+const TracerName = "github.com/bernardosecades/sharesecret/internal/storage/mysql"
+
+// conn is a connection pool or a transaction
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// startSpan starts the span of a statement named by its operation (e.g. "SELECT"), the statement has placeholders
+// so the values (e.g. the content of a secret) are never in the span
+func startSpan(ctx context.Context, statement string) (context.Context, trace.Span) {
+
+	operation := statement
+	if i := strings.IndexByte(statement, ' '); i > 0 {
+		operation = statement[:i]
+	}
+
+	return otel.Tracer(TracerName).Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperationKey.String(operation), semconv.DBStatementKey.String(statement)),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func execContext(ctx context.Context, c conn, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSpan(ctx, query)
+	res, err := c.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+
+	return res, err
+}
+
+func queryContext(ctx context.Context, c conn, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startSpan(ctx, query)
+	rows, err := c.QueryContext(ctx, query, args...)
+	endSpan(span, err)
+
+	return rows, err
+}
+
+// queryRowContext traces the query until the row is returned, it is scanned after the end of the span
+func queryRowContext(ctx context.Context, c conn, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSpan(ctx, query)
+	row := c.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+
+	return row
+}
+
+func commit(ctx context.Context, tx *sql.Tx) error {
+	_, span := startSpan(ctx, "COMMIT")
+	err := tx.Commit()
+	endSpan(span, err)
+
+	return err
+}
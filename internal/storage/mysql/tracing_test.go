@@ -0,0 +1,79 @@
+// +build unit
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// stubConn returns err on every statement
+type stubConn struct {
+	err error
+}
+
+func (c stubConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, c.err
+}
+
+func (c stubConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, c.err
+}
+
+func (c stubConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+
+	return sr
+}
+
+func TestStatementSpan(t *testing.T) {
+
+	sr := recordSpans(t)
+
+	statement := "DELETE FROM secret WHERE id = ?"
+	_, err := execContext(context.Background(), stubConn{}, statement, "727d7040-aac7-4dc3-ab44-938bfba92ebd")
+
+	assert.Nil(t, err)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "DELETE", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Contains(t, spans[0].Attributes(), semconv.DBSystemMySQL)
+	assert.Contains(t, spans[0].Attributes(), semconv.DBStatementKey.String(statement))
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestStatementSpanWithError(t *testing.T) {
+
+	sr := recordSpans(t)
+
+	_, err1 := queryContext(context.Background(), stubConn{err: errors.New("connection refused")}, "SELECT 1")
+	_, err2 := queryContext(context.Background(), stubConn{err: sql.ErrNoRows}, "SELECT 1")
+
+	assert.NotNil(t, err1)
+	assert.Equal(t, sql.ErrNoRows, err2)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	// a secret not found is not an error of the storage
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
@@ -0,0 +1,43 @@
+// Package storage has what the SQL storages of the secrets (mysql and postgres) share.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	// MaxPingBackoff is the longest wait between two pings
+	MaxPingBackoff = 30 * time.Second
+	// pingTimeout is the longest wait for each ping, a host that drops the packets would block it until the TCP timeout
+	pingTimeout = 5 * time.Second
+)
+
+// Ping checks that the connection is valid, retrying while the database starts (e.g. in docker-compose) until
+// ctx is done. It waits backoff after the first failure and doubles it (up to MaxPingBackoff) for the next retries.
+func Ping(ctx context.Context, d *sql.DB, retries int, backoff time.Duration) error {
+
+	err := pingWithTimeout(ctx, d)
+	for i := 0; i < retries && err != nil; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > MaxPingBackoff {
+			backoff = MaxPingBackoff
+		}
+		err = pingWithTimeout(ctx, d)
+	}
+
+	return err
+}
+
+func pingWithTimeout(ctx context.Context, d *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	return d.PingContext(ctx)
+}
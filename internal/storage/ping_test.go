@@ -0,0 +1,68 @@
+// +build unit
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errDown = errors.New("database down")
+
+// downDriver fails to open every connection, as a database not started yet
+type downDriver struct {
+	opens int32
+}
+
+func (d *downDriver) Open(string) (driver.Conn, error) {
+	atomic.AddInt32(&d.opens, 1)
+	return nil, errDown
+}
+
+func downDB(t *testing.T) (*sql.DB, *downDriver) {
+
+	d := &downDriver{}
+	sql.Register(t.Name(), d)
+
+	db, err := sql.Open(t.Name(), "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	return db, d
+}
+
+func TestPingReturnsErrorAfterRetries(t *testing.T) {
+
+	db, d := downDB(t)
+	defer db.Close()
+
+	start := time.Now()
+	err := Ping(context.Background(), db, 2, 10*time.Millisecond)
+
+	assert.True(t, errors.Is(err, errDown))
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(30*time.Millisecond))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&d.opens))
+}
+
+func TestPingStopsRetriesWhenContextIsDone(t *testing.T) {
+
+	db, _ := downDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := Ping(ctx, db, 10, time.Hour)
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
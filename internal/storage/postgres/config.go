@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/bernardosecades/sharesecret/internal/storage"
+)
+
+// Config configures the connection to PostgreSQL. Pool settings with zero value keep the defaults of database/sql.
+type Config struct {
+	Name string
+	User string
+	Pass string
+	Host string
+	Port string
+
+	// SSLMode is the sslmode parameter of the driver: "disable", "require", "verify-ca" or "verify-full"
+	SSLMode string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// PingRetries is the number of pings after the first one fails, waiting PingBackoff and doubling it (up to
+	// storage.MaxPingBackoff) between them
+	PingRetries int
+	PingBackoff time.Duration
+}
+
+// dataSourceName returns the URL of the config
+func (c Config) dataSourceName() string {
+
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Pass),
+		Host:   net.JoinHostPort(c.Host, c.Port),
+		Path:   c.Name,
+	}
+	if len(c.SSLMode) > 0 {
+		u.RawQuery = url.Values{"sslmode": []string{c.SSLMode}}.Encode()
+	}
+
+	return u.String()
+}
+
+func open(ctx context.Context, c Config) (*sql.DB, error) {
+
+	d, err := sql.Open("postgres", c.dataSourceName())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.MaxOpenConns > 0 {
+		d.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		d.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		d.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+
+	if err := storage.Ping(ctx, d, c.PingRetries, c.PingBackoff); err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
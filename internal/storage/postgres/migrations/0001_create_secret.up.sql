@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS secret (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    content text NOT NULL,
+    custom_pwd boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    expired_at timestamp NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+);
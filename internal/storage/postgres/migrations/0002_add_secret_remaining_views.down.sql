@@ -0,0 +1 @@
+ALTER TABLE secret DROP COLUMN IF EXISTS remaining_views;
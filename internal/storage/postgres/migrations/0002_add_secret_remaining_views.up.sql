@@ -0,0 +1 @@
+ALTER TABLE secret ADD COLUMN IF NOT EXISTS remaining_views int NOT NULL DEFAULT 1;
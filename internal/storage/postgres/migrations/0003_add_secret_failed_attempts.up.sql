@@ -0,0 +1 @@
+ALTER TABLE secret ADD COLUMN IF NOT EXISTS failed_attempts int NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE secret ADD COLUMN IF NOT EXISTS deletion_token_hash varchar(64) NOT NULL DEFAULT '';
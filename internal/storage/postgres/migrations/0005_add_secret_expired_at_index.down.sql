@@ -0,0 +1 @@
+DROP INDEX IF EXISTS secret_expired_at_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS secret_expired_at_idx ON secret (expired_at);
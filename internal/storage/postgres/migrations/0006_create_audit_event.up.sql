@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS audit_event (
+    id bigserial NOT NULL PRIMARY KEY,
+    occurred_at timestamp NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    event varchar(32) NOT NULL,
+    secret_id varchar(36) NOT NULL DEFAULT '',
+    count bigint NOT NULL DEFAULT 0
+);
@@ -0,0 +1,21 @@
+// +build unit
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/bernardosecades/sharesecret/internal/storage/migration"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+
+	m, err := migration.Load(migrations, "migrations")
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, m)
+	assert.Equal(t, int64(1), m[0].Version)
+	assert.Equal(t, "create_secret", m[0].Name)
+}
@@ -0,0 +1,255 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/audit"
+	"github.com/bernardosecades/sharesecret/internal/storage/migration"
+
+	_ "github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	formatDate    = "2006-01-02 15:04:05"
+	secretColumns = "id, content, custom_pwd, created_at, expired_at, remaining_views, failed_attempts, deletion_token_hash"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLock is an advisory lock of PostgreSQL, its key is the hash of the name
+var migrationLock = migration.Lock{
+	Acquire: "SELECT pg_advisory_lock(hashtext('sharesecret_migrations'))",
+	Release: "SELECT pg_advisory_unlock(hashtext('sharesecret_migrations'))",
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type postgresSecretRepository struct {
+	SQL *sql.DB
+}
+
+// NewPostgresSecretRepository returns a repository connected to PostgreSQL, an error if it can not connect before
+// ctx is done
+func NewPostgresSecretRepository(ctx context.Context, c Config) (sharesecret.SecretRepository, error) {
+	d, err := open(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresSecretRepository{SQL: d}, nil
+}
+
+// NewMigrator returns a migrator with the migrations of the secret and audit_event tables
+func NewMigrator(ctx context.Context, c Config) (*migration.Migrator, error) {
+	d, err := open(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migration.NewMigrator(d, migrations, "migrations", migrationLock)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Close closes the connections of the pool
+func (r *postgresSecretRepository) Close() error {
+	return r.SQL.Close()
+}
+
+func (r *postgresSecretRepository) GetSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	res := r.SQL.QueryRowContext(ctx, "SELECT "+secretColumns+" FROM secret WHERE id = $1 AND expired_at > $2", id, time.Now().UTC().Format(formatDate))
+
+	return scanSecret(res)
+}
+
+func (r *postgresSecretRepository) CreateSecret(ctx context.Context, content string, customPwd bool, expire time.Time, maxViews int, deletionTokenHash string) (sharesecret.Secret, error) {
+
+	u := uuid.Must(uuid.NewV4(), nil)
+	id := u.String()
+
+	secret := sharesecret.Secret{ID: id, Content: content, CustomPwd: customPwd, CreatedAt: time.Now().UTC(), ExpiredAt: expire, RemainingViews: maxViews, DeletionTokenHash: deletionTokenHash}
+
+	_, err := r.SQL.ExecContext(ctx, "INSERT INTO secret ("+secretColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", secret.ID, secret.Content, secret.CustomPwd, secret.CreatedAt.Format(formatDate), secret.ExpiredAt.UTC().Format(formatDate), secret.RemainingViews, secret.FailedAttempts, secret.DeletionTokenHash)
+
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+func (r *postgresSecretRepository) RemoveSecret(ctx context.Context, id string) error {
+
+	res, err := r.SQL.ExecContext(ctx, "DELETE FROM secret WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if ra == 0 {
+		return sharesecret.ErrSecretNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresSecretRepository) ClaimSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	tx, err := r.SQL.BeginTx(ctx, nil)
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+	defer tx.Rollback()
+
+	// the row stays locked until the end of the transaction so concurrent claims wait and then see the view consumed
+	res := tx.QueryRowContext(ctx, "SELECT "+secretColumns+" FROM secret WHERE id = $1 AND expired_at > $2 FOR UPDATE", id, time.Now().UTC().Format(formatDate))
+	secret, err := scanSecret(res)
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	secret.RemainingViews--
+	if secret.RemainingViews > 0 {
+		_, err = tx.ExecContext(ctx, "UPDATE secret SET remaining_views = $1 WHERE id = $2", secret.RemainingViews, id)
+	} else {
+		secret.RemainingViews = 0
+		_, err = tx.ExecContext(ctx, "DELETE FROM secret WHERE id = $1", id)
+	}
+
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+func (r *postgresSecretRepository) IncrementFailedAttempts(ctx context.Context, id string) (int, error) {
+
+	var attempts int
+	err := r.SQL.QueryRowContext(ctx, "UPDATE secret SET failed_attempts = failed_attempts + 1 WHERE id = $1 AND expired_at > $2 RETURNING failed_attempts", id, time.Now().UTC().Format(formatDate)).Scan(&attempts)
+	if err != nil {
+		return 0, notFound(err)
+	}
+
+	return attempts, nil
+}
+
+func (r *postgresSecretRepository) HasSecretWithCustomPwd(ctx context.Context, id string) (bool, error) {
+
+	secret, err := r.GetSecret(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return secret.CustomPwd, nil
+}
+
+func (r *postgresSecretRepository) GetSecrets(ctx context.Context) ([]sharesecret.Secret, error) {
+
+	rows, err := r.SQL.QueryContext(ctx, "SELECT "+secretColumns+" FROM secret WHERE expired_at > $1", time.Now().UTC().Format(formatDate))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []sharesecret.Secret
+	for rows.Next() {
+		secret, err := scanSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, rows.Err()
+}
+
+func (r *postgresSecretRepository) UpdateSecretContent(ctx context.Context, id string, content string) error {
+
+	_, err := r.SQL.ExecContext(ctx, "UPDATE secret SET content = $1 WHERE id = $2", content, id)
+
+	return err
+}
+
+func (r *postgresSecretRepository) RemoveSecretsExpired(ctx context.Context) ([]string, error) {
+
+	rows, err := r.SQL.QueryContext(ctx, "DELETE FROM secret WHERE expired_at <= $1 RETURNING id", time.Now().UTC().Format(formatDate))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *postgresSecretRepository) CountSecrets(ctx context.Context) (int64, error) {
+
+	var n int64
+	err := r.SQL.QueryRowContext(ctx, "SELECT COUNT(*) FROM secret WHERE expired_at > $1", time.Now().UTC().Format(formatDate)).Scan(&n)
+
+	return n, err
+}
+
+// Record stores the event in the audit_event table, the repository is an audit.Sink
+func (r *postgresSecretRepository) Record(ctx context.Context, e audit.Event) error {
+
+	_, err := r.SQL.ExecContext(ctx, "INSERT INTO audit_event (occurred_at, event, secret_id, count) VALUES ($1, $2, $3, $4)", e.Time.UTC().Format(formatDate), string(e.Type), e.SecretID, e.Count)
+
+	return err
+}
+
+func scanSecret(s scanner) (sharesecret.Secret, error) {
+
+	var secret sharesecret.Secret
+	err := s.Scan(&secret.ID, &secret.Content, &secret.CustomPwd, &secret.CreatedAt, &secret.ExpiredAt, &secret.RemainingViews, &secret.FailedAttempts, &secret.DeletionTokenHash)
+
+	if err != nil {
+		return sharesecret.Secret{}, notFound(err)
+	}
+
+	// timestamp columns have no time zone, values are stored in UTC
+	secret.CreatedAt = secret.CreatedAt.UTC()
+	secret.ExpiredAt = secret.ExpiredAt.UTC()
+
+	return secret, nil
+}
+
+// notFound returns sharesecret.ErrSecretNotFound when the query returned no row
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return sharesecret.ErrSecretNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,166 @@
+// +build integration
+
+package postgres
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/audit"
+	"github.com/bernardosecades/sharesecret/internal/storage/storagetest"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var pr sharesecret.SecretRepository
+
+func init() {
+
+	m, err := NewMigrator(context.Background(), config())
+	if err != nil {
+		panic(err)
+	}
+	defer m.Close()
+
+	if _, err := m.Up(); err != nil {
+		panic(err)
+	}
+
+	pr, err = NewPostgresSecretRepository(context.Background(), config())
+	if err != nil {
+		panic(err)
+	}
+}
+
+func config() Config {
+	return Config{
+		Name:    os.Getenv("POSTGRES_DB_NAME"),
+		User:    os.Getenv("POSTGRES_DB_USER"),
+		Pass:    os.Getenv("POSTGRES_DB_PASS"),
+		Host:    os.Getenv("POSTGRES_DB_HOST"),
+		Port:    os.Getenv("POSTGRES_DB_PORT"),
+		SSLMode: "disable",
+	}
+}
+
+func TestPostgresMigrationsApplied(t *testing.T) {
+
+	m, err := NewMigrator(context.Background(), config())
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	defer m.Close()
+
+	status, err := m.Status()
+
+	assert.Nil(t, err)
+	for _, s := range status {
+		assert.False(t, s.AppliedAt.IsZero(), "migration %d_%s is pending", s.Version, s.Name)
+	}
+}
+
+func TestPostgresSecretRepository(t *testing.T) {
+
+	storagetest.TestSecretRepository(t, func() sharesecret.SecretRepository {
+		return pr
+	})
+}
+
+// TestPostgresMigrationsUpgradeBaselineSchema starts from the table created by the old schema.sql with a secret of
+// that time, and applies the migrations from two processes at the same time as replicas starting together
+func TestPostgresMigrationsUpgradeBaselineSchema(t *testing.T) {
+
+	// in a database of its own, the other tests of the package use the shared one at the same time
+	cfg := config()
+	cfg.Name = "sharesecret_upgrade_" + uuid.Must(uuid.NewV4(), nil).String()[:8]
+
+	shared := pr.(*postgresSecretRepository).SQL
+	if _, err := shared.Exec("CREATE DATABASE " + cfg.Name); err != nil {
+		t.Fatalf("Failed to create the database: %v", err)
+	}
+	defer shared.Exec("DROP DATABASE " + cfg.Name)
+
+	r, err := NewPostgresSecretRepository(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewPostgresSecretRepository failed: %v", err)
+	}
+	defer r.(*postgresSecretRepository).Close()
+
+	m, err := NewMigrator(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	defer m.Close()
+
+	db := r.(*postgresSecretRepository).SQL
+	if _, err := db.Exec(`CREATE TABLE secret (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    content text NOT NULL,
+    custom_pwd boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    expired_at timestamp NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+)`); err != nil {
+		t.Fatalf("Failed to create the baseline schema: %v", err)
+	}
+
+	id := uuid.Must(uuid.NewV4(), nil).String()
+	expire := time.Now().UTC().Add(time.Hour).Format(formatDate)
+	if _, err := db.Exec("INSERT INTO secret (id, content, created_at, expired_at) VALUES ($1, $2, $3, $4)", id, "cb98267468c271c1a09bd6d03a919a2af89e9bde934b409258e9e462e2a7b312a9e6cb4d92582155f7a7c48922", time.Now().UTC().Format(formatDate), expire); err != nil {
+		t.Fatalf("Failed to insert the baseline secret: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			replica, err := NewMigrator(context.Background(), cfg)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer replica.Close()
+
+			_, errs[i] = replica.Up()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Nil(t, errs[0])
+	assert.Nil(t, errs[1])
+
+	status, err := m.Status()
+
+	assert.Nil(t, err)
+	for _, s := range status {
+		assert.False(t, s.AppliedAt.IsZero(), "migration %d_%s is pending", s.Version, s.Name)
+	}
+
+	secret, err := r.GetSecret(context.Background(), id)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, secret.RemainingViews)
+	assert.Equal(t, 0, secret.FailedAttempts)
+	assert.Empty(t, secret.DeletionTokenHash)
+}
+
+func TestPostgresRecordAuditEvent(t *testing.T) {
+
+	id := uuid.Must(uuid.NewV4(), nil).String()
+	err1 := pr.(audit.Sink).Record(context.Background(), audit.NewEvent(audit.EventCreated, id))
+
+	assert.Nil(t, err1)
+
+	var event string
+	err2 := pr.(*postgresSecretRepository).SQL.QueryRow("SELECT event FROM audit_event WHERE secret_id = $1", id).Scan(&event)
+
+	assert.Nil(t, err2)
+	assert.Equal(t, string(audit.EventCreated), event)
+}
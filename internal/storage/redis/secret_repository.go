@@ -0,0 +1,238 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+
+	"github.com/go-redis/redis/v8"
+	uuid "github.com/satori/go.uuid"
+)
+
+// keyPrefix is the prefix of the hashes with the secrets, a hash expires (PEXPIREAT) with its secret
+const keyPrefix = "sharesecret:secret:"
+
+// claimScript decrements the views of the secret and deletes it (burn after reading) when no views remain,
+// it returns the secret before the decrement. Scripts run atomically so concurrent claims see the view consumed.
+var claimScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+local secret = redis.call('HGETALL', KEYS[1])
+if redis.call('HINCRBY', KEYS[1], 'remaining_views', -1) <= 0 then
+	redis.call('DEL', KEYS[1])
+end
+return secret
+`)
+
+// incrementScript increments the field ARGV[1] of an existing secret (HINCRBY alone would create the hash)
+var incrementScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+`)
+
+// updateScript sets the field ARGV[1] of an existing secret (HSET alone would create the hash)
+var updateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+return 1
+`)
+
+type redisSecretRepository struct {
+	Client *redis.Client
+}
+
+// NewRedisSecretRepository returns a repository where the secrets expire with the redis keys so there is nothing to purge
+func NewRedisSecretRepository(addr string, password string, db int) (sharesecret.SecretRepository, error) {
+
+	c := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+	if err := c.Ping(context.Background()).Err(); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return &redisSecretRepository{Client: c}, nil
+}
+
+// Close closes the connections of the client
+func (r *redisSecretRepository) Close() error {
+	return r.Client.Close()
+}
+
+func (r *redisSecretRepository) GetSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	values, err := r.Client.HGetAll(ctx, keyPrefix+id).Result()
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	return secretFromHash(id, values)
+}
+
+func (r *redisSecretRepository) CreateSecret(ctx context.Context, content string, customPwd bool, expire time.Time, maxViews int, deletionTokenHash string) (sharesecret.Secret, error) {
+
+	u := uuid.Must(uuid.NewV4(), nil)
+	id := u.String()
+
+	secret := sharesecret.Secret{ID: id, Content: content, CustomPwd: customPwd, CreatedAt: time.Now().UTC(), ExpiredAt: expire, RemainingViews: maxViews, DeletionTokenHash: deletionTokenHash}
+
+	_, err := r.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, keyPrefix+id, map[string]interface{}{
+			"content":             secret.Content,
+			"custom_pwd":          strconv.FormatBool(secret.CustomPwd),
+			"created_at":          secret.CreatedAt.Format(time.RFC3339Nano),
+			"expired_at":          secret.ExpiredAt.UTC().Format(time.RFC3339Nano),
+			"remaining_views":     secret.RemainingViews,
+			"failed_attempts":     secret.FailedAttempts,
+			"deletion_token_hash": secret.DeletionTokenHash,
+		})
+		p.PExpireAt(ctx, keyPrefix+id, secret.ExpiredAt)
+		return nil
+	})
+
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+func (r *redisSecretRepository) RemoveSecret(ctx context.Context, id string) error {
+
+	n, err := r.Client.Del(ctx, keyPrefix+id).Result()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return sharesecret.ErrSecretNotFound
+	}
+
+	return nil
+}
+
+func (r *redisSecretRepository) ClaimSecret(ctx context.Context, id string) (sharesecret.Secret, error) {
+
+	v, err := claimScript.Run(ctx, r.Client, []string{keyPrefix + id}).Result()
+	if err == redis.Nil {
+		return sharesecret.Secret{}, sharesecret.ErrSecretNotFound
+	}
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	res, _ := v.([]interface{})
+
+	values := make(map[string]string, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		field, _ := res[i].(string)
+		value, _ := res[i+1].(string)
+		values[field] = value
+	}
+
+	secret, err := secretFromHash(id, values)
+	if err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	secret.RemainingViews--
+	if secret.RemainingViews < 0 {
+		secret.RemainingViews = 0
+	}
+
+	return secret, nil
+}
+
+func (r *redisSecretRepository) IncrementFailedAttempts(ctx context.Context, id string) (int, error) {
+
+	attempts, err := incrementScript.Run(ctx, r.Client, []string{keyPrefix + id}, "failed_attempts").Int()
+	if err == redis.Nil {
+		return 0, sharesecret.ErrSecretNotFound
+	}
+
+	return attempts, err
+}
+
+func (r *redisSecretRepository) HasSecretWithCustomPwd(ctx context.Context, id string) (bool, error) {
+
+	secret, err := r.GetSecret(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return secret.CustomPwd, nil
+}
+
+func (r *redisSecretRepository) GetSecrets(ctx context.Context) ([]sharesecret.Secret, error) {
+
+	var secrets []sharesecret.Secret
+	iter := r.Client.Scan(ctx, 0, keyPrefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		id := iter.Val()[len(keyPrefix):]
+		secret, err := r.GetSecret(ctx, id)
+		if err == sharesecret.ErrSecretNotFound {
+			continue // expired or removed after the scan
+		}
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, iter.Err()
+}
+
+// CountSecrets counts the keys of the secrets, redis removes them when they expire
+func (r *redisSecretRepository) CountSecrets(ctx context.Context) (int64, error) {
+
+	var n int64
+	iter := r.Client.Scan(ctx, 0, keyPrefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		n++
+	}
+
+	return n, iter.Err()
+}
+
+func (r *redisSecretRepository) UpdateSecretContent(ctx context.Context, id string, content string) error {
+
+	return updateScript.Run(ctx, r.Client, []string{keyPrefix + id}, "content", content).Err()
+}
+
+// RemoveSecretsExpired does nothing, redis removes the secrets when they expire
+func (r *redisSecretRepository) RemoveSecretsExpired(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func secretFromHash(id string, values map[string]string) (sharesecret.Secret, error) {
+
+	if len(values) == 0 {
+		return sharesecret.Secret{}, sharesecret.ErrSecretNotFound
+	}
+
+	secret := sharesecret.Secret{ID: id, Content: values["content"], DeletionTokenHash: values["deletion_token_hash"]}
+
+	var err error
+	if secret.CustomPwd, err = strconv.ParseBool(values["custom_pwd"]); err != nil {
+		return sharesecret.Secret{}, err
+	}
+	if secret.CreatedAt, err = time.Parse(time.RFC3339Nano, values["created_at"]); err != nil {
+		return sharesecret.Secret{}, err
+	}
+	if secret.ExpiredAt, err = time.Parse(time.RFC3339Nano, values["expired_at"]); err != nil {
+		return sharesecret.Secret{}, err
+	}
+	if secret.RemainingViews, err = strconv.Atoi(values["remaining_views"]); err != nil {
+		return sharesecret.Secret{}, err
+	}
+	if secret.FailedAttempts, err = strconv.Atoi(values["failed_attempts"]); err != nil {
+		return sharesecret.Secret{}, err
+	}
+
+	return secret, nil
+}
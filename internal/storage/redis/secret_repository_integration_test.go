@@ -0,0 +1,30 @@
+// +build integration
+
+package redis
+
+import (
+	"os"
+	"testing"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/storage/storagetest"
+)
+
+var rr sharesecret.SecretRepository
+
+func init() {
+
+	r, err := NewRedisSecretRepository(os.Getenv("REDIS_ADDR"), os.Getenv("REDIS_PASSWORD"), 0)
+	if err != nil {
+		panic(err)
+	}
+
+	rr = r
+}
+
+func TestRedisServerSecretRepository(t *testing.T) {
+
+	storagetest.TestSecretRepository(t, func() sharesecret.SecretRepository {
+		return rr
+	})
+}
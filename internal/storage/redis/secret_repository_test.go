@@ -0,0 +1,52 @@
+// +build unit
+
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+	"github.com/bernardosecades/sharesecret/internal/storage/storagetest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMiniredisSecretRepository(t *testing.T) (sharesecret.SecretRepository, *miniredis.Miniredis) {
+
+	m := miniredis.RunT(t)
+	r, err := NewRedisSecretRepository(m.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("NewRedisSecretRepository failed: %v", err)
+	}
+
+	return r, m
+}
+
+func TestRedisSecretRepository(t *testing.T) {
+
+	storagetest.TestSecretRepository(t, func() sharesecret.SecretRepository {
+		r, _ := newMiniredisSecretRepository(t)
+		return r
+	})
+}
+
+func TestRedisSecretRepositorySecretExpiresWithKey(t *testing.T) {
+
+	r, m := newMiniredisSecretRepository(t)
+
+	r1, err1 := r.CreateSecret(context.Background(), "this is a test secret expires with key", false, time.Now().UTC().Add(time.Hour), 1, "")
+
+	assert.Nil(t, err1)
+	assert.True(t, m.Exists(keyPrefix+r1.ID))
+	assert.InDelta(t, time.Hour, m.TTL(keyPrefix+r1.ID), float64(time.Second))
+
+	m.FastForward(time.Hour)
+
+	_, err2 := r.GetSecret(context.Background(), r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err2)
+	assert.False(t, m.Exists(keyPrefix+r1.ID))
+}
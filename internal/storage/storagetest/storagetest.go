@@ -0,0 +1,253 @@
+// Package storagetest implements the tests that every sharesecret.SecretRepository must pass.
+//
+// Each backend calls TestSecretRepository from its own tests, repositories can share data between
+// the tests (e.g. a database used by other tests) so they only check the secrets they create.
+package storagetest
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sharesecret "github.com/bernardosecades/sharesecret/internal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const deletionTokenHash = "03ac674216f3e15c761ee1a5e255f067953623c8b388b4459e13f978d7c846f4"
+
+// TestSecretRepository runs the conformance tests, newRepository is called once per test
+func TestSecretRepository(t *testing.T, newRepository func() sharesecret.SecretRepository) {
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, r sharesecret.SecretRepository)
+	}{
+		{"CreateAndReadSecretNoExpired", testCreateAndReadSecretNoExpired},
+		{"CreateAndReadSecretExpired", testCreateAndReadSecretExpired},
+		{"RemoveSecret", testRemoveSecret},
+		{"GetSecretsAndUpdateSecretContent", testGetSecretsAndUpdateSecretContent},
+		{"CountSecrets", testCountSecrets},
+		{"ClaimSecret", testClaimSecret},
+		{"ClaimSecretConcurrently", testClaimSecretConcurrently},
+		{"IncrementFailedAttempts", testIncrementFailedAttempts},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository())
+		})
+	}
+}
+
+func testCreateAndReadSecretNoExpired(t *testing.T, r sharesecret.SecretRepository) {
+
+	ctx := context.Background()
+	tm := time.Now().UTC().Add(time.Hour)
+	r1, err1 := r.CreateSecret(ctx, "this is a test create and read secret not expired", true, tm, 2, deletionTokenHash)
+
+	assert.Nil(t, err1)
+	assert.Len(t, r1.ID, 36)
+	assert.Equal(t, 2, r1.RemainingViews)
+
+	r2, err2 := r.HasSecretWithCustomPwd(ctx, r1.ID)
+
+	assert.Nil(t, err2)
+	assert.True(t, r2)
+
+	r3, err3 := r.GetSecret(ctx, r1.ID)
+
+	assert.Nil(t, err3)
+	assert.Equal(t, r1.ID, r3.ID)
+	assert.Equal(t, "this is a test create and read secret not expired", r3.Content)
+	assert.True(t, r3.CustomPwd)
+	assert.WithinDuration(t, tm, r3.ExpiredAt, time.Second)
+	assert.Equal(t, 2, r3.RemainingViews)
+	assert.Equal(t, 0, r3.FailedAttempts)
+	assert.Equal(t, deletionTokenHash, r3.DeletionTokenHash)
+
+	assert.Nil(t, r.RemoveSecret(ctx, r1.ID))
+}
+
+func testCreateAndReadSecretExpired(t *testing.T, r sharesecret.SecretRepository) {
+
+	ctx := context.Background()
+	tm := time.Now().UTC().Add(-1 * time.Hour)
+	r1, err1 := r.CreateSecret(ctx, "this is a test create and read secret expired", true, tm, 1, "")
+
+	assert.Nil(t, err1)
+
+	r2, err2 := r.HasSecretWithCustomPwd(ctx, r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err2)
+	assert.False(t, r2)
+
+	_, err3 := r.GetSecret(ctx, r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err3)
+
+	_, err4 := r.ClaimSecret(ctx, r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err4)
+
+	_, err5 := r.IncrementFailedAttempts(ctx, r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err5)
+
+	r6, err6 := r.GetSecrets(ctx)
+
+	assert.Nil(t, err6)
+	for _, s := range r6 {
+		assert.NotEqual(t, r1.ID, s.ID)
+	}
+
+	// storages with native expiry (e.g. redis) have nothing to remove
+	_, err7 := r.RemoveSecretsExpired(ctx)
+
+	assert.Nil(t, err7)
+
+	r8, err8 := r.RemoveSecretsExpired(ctx)
+
+	assert.Nil(t, err8)
+	assert.Empty(t, r8)
+}
+
+func testRemoveSecret(t *testing.T, r sharesecret.SecretRepository) {
+
+	ctx := context.Background()
+	tm := time.Now().UTC().Add(time.Hour)
+	r1, _ := r.CreateSecret(ctx, "this is a test remove secret", false, tm, 1, "")
+
+	assert.Nil(t, r.RemoveSecret(ctx, r1.ID))
+
+	_, err2 := r.GetSecret(ctx, r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err2)
+	assert.Equal(t, sharesecret.ErrSecretNotFound, r.RemoveSecret(ctx, r1.ID))
+}
+
+func testGetSecretsAndUpdateSecretContent(t *testing.T, r sharesecret.SecretRepository) {
+
+	ctx := context.Background()
+	tm := time.Now().UTC().Add(time.Hour)
+	r1, _ := r.CreateSecret(ctx, "this is a test update secret content", false, tm, 1, "")
+
+	r2, err2 := r.GetSecrets(ctx)
+
+	assert.Nil(t, err2)
+
+	var ids []string
+	for _, s := range r2 {
+		ids = append(ids, s.ID)
+	}
+	assert.Contains(t, ids, r1.ID)
+
+	assert.Nil(t, r.UpdateSecretContent(ctx, r1.ID, "this is the secret content updated"))
+
+	r4, err4 := r.GetSecret(ctx, r1.ID)
+
+	assert.Nil(t, err4)
+	assert.Equal(t, "this is the secret content updated", r4.Content)
+
+	assert.Nil(t, r.RemoveSecret(ctx, r1.ID))
+}
+
+func testCountSecrets(t *testing.T, r sharesecret.SecretRepository) {
+
+	ctx := context.Background()
+	r1, _ := r.CreateSecret(ctx, "this is a test count secrets", false, time.Now().UTC().Add(time.Hour), 1, "")
+
+	r2, err2 := r.CountSecrets(ctx)
+
+	assert.Nil(t, err2)
+	assert.GreaterOrEqual(t, r2, int64(1))
+
+	assert.Nil(t, r.RemoveSecret(ctx, r1.ID))
+}
+
+func testClaimSecret(t *testing.T, r sharesecret.SecretRepository) {
+
+	ctx := context.Background()
+	tm := time.Now().UTC().Add(time.Hour)
+	r1, _ := r.CreateSecret(ctx, "this is a test claim secret", false, tm, 2, "")
+
+	r2, err2 := r.ClaimSecret(ctx, r1.ID)
+
+	assert.Nil(t, err2)
+	assert.Equal(t, "this is a test claim secret", r2.Content)
+	assert.Equal(t, 1, r2.RemainingViews)
+
+	r3, err3 := r.GetSecret(ctx, r1.ID)
+
+	assert.Nil(t, err3)
+	assert.Equal(t, 1, r3.RemainingViews)
+
+	r4, err4 := r.ClaimSecret(ctx, r1.ID)
+
+	assert.Nil(t, err4)
+	assert.Equal(t, "this is a test claim secret", r4.Content)
+	assert.Equal(t, 0, r4.RemainingViews)
+
+	_, err5 := r.GetSecret(ctx, r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err5)
+
+	_, err6 := r.ClaimSecret(ctx, r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err6)
+}
+
+func testClaimSecretConcurrently(t *testing.T, r sharesecret.SecretRepository) {
+
+	ctx := context.Background()
+	tm := time.Now().UTC().Add(time.Hour)
+	r1, _ := r.CreateSecret(ctx, "this is a test claim secret concurrently", false, tm, 1, "")
+
+	const readers = 10
+	var wg sync.WaitGroup
+	var claimed int32
+
+	wg.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := r.ClaimSecret(ctx, r1.ID); err == nil {
+				atomic.AddInt32(&claimed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), claimed)
+}
+
+func testIncrementFailedAttempts(t *testing.T, r sharesecret.SecretRepository) {
+
+	ctx := context.Background()
+	tm := time.Now().UTC().Add(time.Hour)
+	r1, _ := r.CreateSecret(ctx, "this is a test increment failed attempts", true, tm, 1, "")
+
+	r2, err2 := r.IncrementFailedAttempts(ctx, r1.ID)
+
+	assert.Nil(t, err2)
+	assert.Equal(t, 1, r2)
+
+	r3, err3 := r.IncrementFailedAttempts(ctx, r1.ID)
+
+	assert.Nil(t, err3)
+	assert.Equal(t, 2, r3)
+
+	r4, err4 := r.GetSecret(ctx, r1.ID)
+
+	assert.Nil(t, err4)
+	assert.Equal(t, 2, r4.FailedAttempts)
+
+	assert.Nil(t, r.RemoveSecret(ctx, r1.ID))
+
+	_, err5 := r.IncrementFailedAttempts(ctx, r1.ID)
+
+	assert.Equal(t, sharesecret.ErrSecretNotFound, err5)
+}
@@ -0,0 +1,82 @@
+package sharesecret
+
+import (
+	"context"
+	"time"
+)
+
+// timeoutRepository calls the repository with a context that expires after timeout
+type timeoutRepository struct {
+	repository SecretRepository
+	timeout    time.Duration
+}
+
+func (r timeoutRepository) GetSecret(ctx context.Context, id string) (Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.GetSecret(ctx, id)
+}
+
+func (r timeoutRepository) CreateSecret(ctx context.Context, content string, customPwd bool, expire time.Time, maxViews int, deletionTokenHash string) (Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.CreateSecret(ctx, content, customPwd, expire, maxViews, deletionTokenHash)
+}
+
+func (r timeoutRepository) ClaimSecret(ctx context.Context, id string) (Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.ClaimSecret(ctx, id)
+}
+
+func (r timeoutRepository) IncrementFailedAttempts(ctx context.Context, id string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.IncrementFailedAttempts(ctx, id)
+}
+
+func (r timeoutRepository) RemoveSecret(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.RemoveSecret(ctx, id)
+}
+
+func (r timeoutRepository) RemoveSecretsExpired(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.RemoveSecretsExpired(ctx)
+}
+
+func (r timeoutRepository) HasSecretWithCustomPwd(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.HasSecretWithCustomPwd(ctx, id)
+}
+
+func (r timeoutRepository) GetSecrets(ctx context.Context) ([]Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.GetSecrets(ctx)
+}
+
+func (r timeoutRepository) UpdateSecretContent(ctx context.Context, id string, content string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.UpdateSecretContent(ctx, id, content)
+}
+
+func (r timeoutRepository) CountSecrets(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.repository.CountSecrets(ctx)
+}
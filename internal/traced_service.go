@@ -0,0 +1,84 @@
+package sharesecret
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer of the secret service
+const TracerName = "github.com/bernardosecades/sharesecret/internal"
+
+// tracedService traces every call to the service with a span, child of the span of ctx (e.g. the one of the gRPC call)
+type tracedService struct {
+	service SecretService
+	tracer  trace.Tracer
+}
+
+// NewTracedSecretService returns s with a span per call from the global tracer provider (no spans until
+// one is set), the spans never have the secret ID (it gives access to the secret), the content nor the password
+func NewTracedSecretService(s SecretService) SecretService {
+	return tracedService{service: s, tracer: otel.Tracer(TracerName)}
+}
+
+func (s tracedService) GetContentSecret(ctx context.Context, id string, password string) (Secret, error) {
+	ctx, span := s.tracer.Start(ctx, "SecretService.GetContentSecret")
+	defer span.End()
+
+	secret, err := s.service.GetContentSecret(ctx, id, password)
+	recordError(span, err)
+
+	return secret, err
+}
+
+func (s tracedService) GetSecretInfo(ctx context.Context, id string) (Secret, error) {
+	ctx, span := s.tracer.Start(ctx, "SecretService.GetSecretInfo")
+	defer span.End()
+
+	secret, err := s.service.GetSecretInfo(ctx, id)
+	recordError(span, err)
+
+	return secret, err
+}
+
+func (s tracedService) CreateSecret(ctx context.Context, rawContent string, password string, ttl time.Duration, maxViews int) (Secret, error) {
+	ctx, span := s.tracer.Start(ctx, "SecretService.CreateSecret")
+	defer span.End()
+
+	secret, err := s.service.CreateSecret(ctx, rawContent, password, ttl, maxViews)
+	recordError(span, err)
+
+	return secret, err
+}
+
+func (s tracedService) DeleteSecret(ctx context.Context, id string, token string) error {
+	ctx, span := s.tracer.Start(ctx, "SecretService.DeleteSecret")
+	defer span.End()
+
+	err := s.service.DeleteSecret(ctx, id, token)
+	recordError(span, err)
+
+	return err
+}
+
+func (s tracedService) RekeySecrets(ctx context.Context) (RekeyResult, error) {
+	ctx, span := s.tracer.Start(ctx, "SecretService.RekeySecrets")
+	defer span.End()
+
+	r, err := s.service.RekeySecrets(ctx)
+	span.SetAttributes(attribute.Int64("secrets.rekeyed", r.Rekeyed), attribute.Int64("secrets.skipped", r.Skipped))
+	recordError(span, err)
+
+	return r, err
+}
+
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
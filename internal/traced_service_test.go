@@ -0,0 +1,98 @@
+// +build unit
+
+package sharesecret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans sets a tracer provider recording the spans until the end of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+
+	return sr
+}
+
+func TestTracedSecretServiceSpans(t *testing.T) {
+
+	sr := recordSpans(t)
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	repo := new(MockRepository)
+	repo.
+		On("GetSecret", id).
+		Return(Secret{ID: id, ExpiredAt: expired, RemainingViews: 1}, nil)
+
+	sut := NewTracedSecretService(NewSecretService(repo, key, pass))
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	_, err := sut.GetSecretInfo(ctx, id)
+	parent.End()
+
+	assert.Nil(t, err)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "SecretService.GetSecretInfo", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.NotContains(t, spans[0].Attributes(), attribute.String("secret.id", id))
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestTracedSecretServiceSpansWithoutContentNorPassword(t *testing.T) {
+
+	sr := recordSpans(t)
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+	id := "727d7040-aac7-4dc3-ab44-938bfba92ebd"
+
+	repo := new(MockRepository)
+	repo.
+		On("CreateSecret", mock.Anything, true, mock.Anything, 1, mock.Anything).
+		Return(Secret{ID: id, CustomPwd: true, ExpiredAt: expired}, nil)
+
+	sut := NewTracedSecretService(NewSecretService(repo, key, pass))
+	_, err := sut.CreateSecret(context.Background(), "this is my secret", "1234", 0, 0)
+
+	assert.Nil(t, err)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "SecretService.CreateSecret", spans[0].Name())
+	assert.Empty(t, spans[0].Attributes())
+}
+
+func TestTracedSecretServiceSpanWithError(t *testing.T) {
+
+	sr := recordSpans(t)
+
+	key := "11111111111111111111111111111111"
+	pass := "@myPassword"
+
+	sut := NewTracedSecretService(NewSecretService(new(MockRepository), key, pass))
+	_, err := sut.CreateSecret(context.Background(), "", "", 0, 0)
+
+	assert.Equal(t, ErrEmptyContent, err)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, ErrEmptyContent.Error(), spans[0].Status().Description)
+	assert.Len(t, spans[0].Events(), 1)
+}
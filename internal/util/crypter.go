@@ -10,6 +10,15 @@ import (
 )
 
 func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	return EncryptWithAdditionalData(key, plaintext, nil)
+}
+
+func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	return DecryptWithAdditionalData(key, ciphertext, nil)
+}
+
+// EncryptWithAdditionalData encrypts and authenticates plaintext, and authenticates (without encrypting) additionalData
+func EncryptWithAdditionalData(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
 	c, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -25,10 +34,11 @@ func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
 }
 
-func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+// DecryptWithAdditionalData decrypts ciphertext, additionalData must match the one used to encrypt
+func DecryptWithAdditionalData(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
 	c, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -45,5 +55,5 @@ func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
 	}
 
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	return gcm.Open(nil, nonce, ciphertext, nil)
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
 }
@@ -37,3 +37,21 @@ func TestEncryptDecryptWithDifferentKey(t *testing.T) {
 	assert.Nil(t, rd)
 	assert.Equal(t, "cipher: message authentication failed", err.Error())
 }
+
+func TestEncryptDecryptWithAdditionalData(t *testing.T) {
+
+	text := []byte("My name is Bernie")
+	key := []byte("11111111111111111111111111111111")
+
+	re, err1 := EncryptWithAdditionalData(key, text, []byte("header"))
+	rd, err2 := DecryptWithAdditionalData(key, re, []byte("header"))
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, text, rd)
+
+	rd, err3 := DecryptWithAdditionalData(key, re, []byte("other header"))
+
+	assert.Nil(t, rd)
+	assert.Equal(t, "cipher: message authentication failed", err3.Error())
+}
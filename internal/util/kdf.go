@@ -0,0 +1,48 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Argon2id parameters, see https://tools.ietf.org/html/draft-irtf-cfrg-argon2 (section 4)
+const (
+	SaltSize = 16
+	KeySize  = 32
+
+	argonTime    = 1
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+// ArgonMemory is the memory in bytes used by every DeriveKey
+const ArgonMemory = argonMemory * 1024
+
+// NewSalt returns SaltSize random bytes to derive a key
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// DeriveKey derives a key of KeySize bytes from the password with Argon2id
+func DeriveKey(password []byte, salt []byte) []byte {
+	return argon2.IDKey(password, salt, argonTime, argonMemory, argonThreads, KeySize)
+}
+
+// ExpandKey derives a key of KeySize bytes from a secret of high entropy (e.g. the default password of the server)
+// with HKDF-SHA256. It is cheap, there is nothing to brute force so DeriveKey would only waste memory.
+func ExpandKey(secret []byte, salt []byte) []byte {
+	key := make([]byte, KeySize)
+	// HKDF can not fail reading KeySize bytes
+	_, _ = io.ReadFull(hkdf.New(sha256.New, secret, salt, nil), key)
+
+	return key
+}
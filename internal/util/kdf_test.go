@@ -0,0 +1,54 @@
+// +build unit
+
+package util
+
+import (
+	"github.com/stretchr/testify/assert"
+
+	"testing"
+)
+
+func TestDeriveKeySameInputSameKey(t *testing.T) {
+
+	salt := []byte("1234567890123456")
+
+	k1 := DeriveKey([]byte("@myPassword"), salt)
+	k2 := DeriveKey([]byte("@myPassword"), salt)
+
+	assert.Len(t, k1, KeySize)
+	assert.Equal(t, k1, k2)
+}
+
+func TestDeriveKeyDifferentSaltOrPassword(t *testing.T) {
+
+	salt := []byte("1234567890123456")
+
+	k := DeriveKey([]byte("@myPassword"), salt)
+
+	assert.NotEqual(t, k, DeriveKey([]byte("@myPassword"), []byte("6543210987654321")))
+	assert.NotEqual(t, k, DeriveKey([]byte("@otherPassword"), salt))
+}
+
+func TestExpandKey(t *testing.T) {
+
+	salt := []byte("1234567890123456")
+
+	k := ExpandKey([]byte("@myPassword"), salt)
+
+	assert.Len(t, k, KeySize)
+	assert.Equal(t, k, ExpandKey([]byte("@myPassword"), salt))
+	assert.NotEqual(t, k, ExpandKey([]byte("@myPassword"), []byte("6543210987654321")))
+	assert.NotEqual(t, k, ExpandKey([]byte("@otherPassword"), salt))
+	assert.NotEqual(t, k, DeriveKey([]byte("@myPassword"), salt))
+}
+
+func TestNewSalt(t *testing.T) {
+
+	s1, err1 := NewSalt()
+	s2, err2 := NewSalt()
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Len(t, s1, SaltSize)
+	assert.NotEqual(t, s1, s2)
+}
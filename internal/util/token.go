@@ -0,0 +1,26 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+const tokenSize = 32
+
+// NewToken returns a random token hex encoded
+func NewToken() (string, error) {
+	token := make([]byte, tokenSize)
+	if _, err := io.ReadFull(rand.Reader, token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// HashToken returns the SHA-256 of the token hex encoded, tokens are random so they do not need a slow hash
+func HashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
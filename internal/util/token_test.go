@@ -0,0 +1,26 @@
+// +build unit
+
+package util
+
+import (
+	"github.com/stretchr/testify/assert"
+
+	"testing"
+)
+
+func TestNewToken(t *testing.T) {
+
+	t1, err1 := NewToken()
+	t2, err2 := NewToken()
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Len(t, t1, 64)
+	assert.NotEqual(t, t1, t2)
+}
+
+func TestHashToken(t *testing.T) {
+
+	assert.Equal(t, "03ac674216f3e15c761ee1a5e255f067953623c8b388b4459e13f978d7c846f4", HashToken("1234"))
+	assert.NotEqual(t, HashToken("1234"), HashToken("12345"))
+}